@@ -0,0 +1,227 @@
+package cobertura
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// decision is a point in the source where control flow takes one of several
+// outcomes, together with how many of them the profile shows as taken.
+type decision struct {
+	Line     int // line of the statement holding the decision
+	Branches int
+	Covered  int
+}
+
+// decisions finds the decision points in body: if/else, switch and type
+// switch cases, select cases, for-loop conditions and the && and || operators
+// in if and for conditions.
+//
+// An outcome counts as taken if the profile block that starts it has hits.
+// Outcomes without a block of their own, like the false branch of an if
+// without else or the implicit default of a switch, are derived from the
+// count of the block holding the decision. Profiles have no counters inside
+// expressions, so the two outcomes of && and || follow the true and false
+// outcomes of the enclosing condition.
+func (v *fileVisitor) decisions(body ast.Node) []decision {
+	next := nextStatements(body)
+	var ds []decision
+	add := func(n ast.Node, taken ...bool) {
+		if len(taken) < 2 {
+			return
+		}
+		d := decision{Line: v.fset.Position(n.Pos()).Line, Branches: len(taken)}
+		for _, t := range taken {
+			if t {
+				d.Covered++
+			}
+		}
+		ds = append(ds, d)
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			// Split function literals have their own decisions.
+			return !v.opts.FuncLits || node == body
+		case *ast.IfStmt:
+			reached, _ := v.countAt(n.Pos())
+			then, _ := v.firstCountIn(n.Body.Lbrace, n.Body.Rbrace)
+			var els bool
+			if n.Else != nil {
+				c, _ := v.firstCountIn(n.Else.Pos(), n.Else.End())
+				els = c > 0
+			} else {
+				els = v.implicitTaken(reached, then, n.Body.List, next[n])
+			}
+			add(n, then > 0, els)
+			v.shortCircuits(n.Cond, then > 0, els, func(taken ...bool) { add(n, taken...) })
+		case *ast.ForStmt:
+			if n.Cond == nil {
+				break
+			}
+			reached, _ := v.countAt(n.Pos())
+			body, _ := v.firstCountIn(n.Body.Lbrace, n.Body.Rbrace)
+			exit := reached > 0
+			if s := next[n]; s != nil {
+				c, _ := v.countAt(s.Pos())
+				exit = exit && c > 0
+			}
+			add(n, body > 0, exit)
+			v.shortCircuits(n.Cond, body > 0, exit, func(taken ...bool) { add(n, taken...) })
+		case *ast.SwitchStmt:
+			add(n, v.clausesTaken(n, n.Body)...)
+		case *ast.TypeSwitchStmt:
+			add(n, v.clausesTaken(n, n.Body)...)
+		case *ast.SelectStmt:
+			var taken []bool
+			for _, s := range n.Body.List {
+				cc := s.(*ast.CommClause)
+				c, _ := v.firstCountIn(cc.Colon, cc.End())
+				taken = append(taken, c > 0)
+			}
+			add(n, taken...)
+		}
+		return true
+	})
+	return ds
+}
+
+// clausesTaken returns the outcomes of a switch: one per case clause, plus the
+// implicit default if there is no default clause.
+func (v *fileVisitor) clausesTaken(n ast.Stmt, body *ast.BlockStmt) []bool {
+	reached, _ := v.countAt(n.Pos())
+	var taken []bool
+	sum, hasDefault := 0, false
+	for _, s := range body.List {
+		cc := s.(*ast.CaseClause)
+		if cc.List == nil {
+			hasDefault = true
+		}
+		c, _ := v.firstCountIn(cc.Colon, cc.End())
+		sum += c
+		taken = append(taken, c > 0)
+	}
+	if !hasDefault {
+		taken = append(taken, v.implicitTaken(reached, sum, nil, nil))
+	}
+	return taken
+}
+
+// implicitTaken reports whether the outcome of a decision that has no block
+// of its own was taken, given how often the decision was reached and how
+// often its explicit outcomes were. In set mode counts are only 0 or 1, so
+// the statement following the decision is used if the explicit outcome
+// (whose statements are in explicit) never falls through to it.
+func (v *fileVisitor) implicitTaken(reached, explicit int, stmts []ast.Stmt, next ast.Stmt) bool {
+	if reached == 0 {
+		return false
+	}
+	if v.profile.Mode != "set" {
+		return reached > explicit
+	}
+	if explicit == 0 {
+		return true
+	}
+	if next != nil && terminates(stmts) {
+		c, _ := v.countAt(next.Pos())
+		return c > 0
+	}
+	return false
+}
+
+// shortCircuits adds a decision with two outcomes, evaluating the right
+// operand or not, for each && and || in cond.
+func (v *fileVisitor) shortCircuits(cond ast.Expr, whenTrue, whenFalse bool, add func(taken ...bool)) {
+	ast.Inspect(cond, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BinaryExpr:
+			switch n.Op {
+			case token.LAND:
+				add(whenTrue, whenFalse)
+			case token.LOR:
+				add(whenFalse, whenTrue)
+			}
+		}
+		return true
+	})
+}
+
+// countAt returns the count of the innermost profile block containing pos.
+func (v *fileVisitor) countAt(pos token.Pos) (int, bool) {
+	p := v.fset.Position(pos)
+	count, found := 0, false
+	for _, b := range v.profile.Blocks {
+		if after(b.StartLine, b.StartCol, p.Line, p.Column) {
+			break
+		}
+		if after(b.EndLine, b.EndCol, p.Line, p.Column) {
+			count, found = b.Count, true
+		}
+	}
+	return count, found
+}
+
+// firstCountIn returns the count of the first profile block starting between
+// from and to, inclusive.
+func (v *fileVisitor) firstCountIn(from, to token.Pos) (int, bool) {
+	f, t := v.fset.Position(from), v.fset.Position(to)
+	for _, b := range v.profile.Blocks {
+		if after(f.Line, f.Column, b.StartLine, b.StartCol) {
+			continue
+		}
+		if after(b.StartLine, b.StartCol, t.Line, t.Column) {
+			break
+		}
+		return b.Count, true
+	}
+	return 0, false
+}
+
+// after reports whether line1.col1 comes after line2.col2.
+func after(line1, col1, line2, col2 int) bool {
+	return line1 > line2 || line1 == line2 && col1 > col2
+}
+
+// nextStatements maps each statement in body to the statement following it
+// in the same list.
+func nextStatements(body ast.Node) map[ast.Stmt]ast.Stmt {
+	next := make(map[ast.Stmt]ast.Stmt)
+	link := func(list []ast.Stmt) {
+		for i := 0; i+1 < len(list); i++ {
+			next[list[i]] = list[i+1]
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BlockStmt:
+			link(n.List)
+		case *ast.CaseClause:
+			link(n.Body)
+		case *ast.CommClause:
+			link(n.Body)
+		}
+		return true
+	})
+	return next
+}
+
+// terminates reports whether a statement list never falls through to the
+// statement after it.
+func terminates(list []ast.Stmt) bool {
+	if len(list) == 0 {
+		return false
+	}
+	switch s := list[len(list)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				return true
+			}
+		}
+	}
+	return false
+}
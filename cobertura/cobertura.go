@@ -0,0 +1,493 @@
+package cobertura
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type Coverage struct {
+	XMLName         xml.Name   `xml:"coverage"`
+	LineRate        float32    `xml:"line-rate,attr"`
+	BranchRate      float32    `xml:"branch-rate,attr"`
+	Version         string     `xml:"version,attr"`
+	Timestamp       int64      `xml:"timestamp,attr"`
+	LinesCovered    int64      `xml:"lines-covered,attr"`
+	LinesValid      int64      `xml:"lines-valid,attr"`
+	BranchesCovered int64      `xml:"branches-covered,attr"`
+	BranchesValid   int64      `xml:"branches-valid,attr"`
+	Complexity      float32    `xml:"complexity,attr"`
+	Comment         string     `xml:",comment"`
+	Sources         []*Source  `xml:"sources>source"`
+	Packages        []*Package `xml:"packages>package"`
+
+	// LinesExcluded counts the lines left out by coverage directives.
+	LinesExcluded int64 `xml:"-"`
+	// Metric is what the line rates measure; CheckThresholds uses it too.
+	Metric RateMetric `xml:"-"`
+}
+
+type Source struct {
+	Path string `xml:",chardata"`
+}
+
+type Package struct {
+	Name       string   `xml:"name,attr"`
+	LineRate   float32  `xml:"line-rate,attr"`
+	BranchRate float32  `xml:"branch-rate,attr"`
+	Complexity float32  `xml:"complexity,attr"`
+	Classes    []*Class `xml:"classes>class"`
+}
+
+type Class struct {
+	Name       string    `xml:"name,attr"`
+	Filename   string    `xml:"filename,attr"`
+	LineRate   float32   `xml:"line-rate,attr"`
+	BranchRate float32   `xml:"branch-rate,attr"`
+	Complexity float32   `xml:"complexity,attr"`
+	Methods    []*Method `xml:"methods>method"`
+	Lines      Lines     `xml:"lines>line"`
+}
+
+type Method struct {
+	Name       string  `xml:"name,attr"`
+	Signature  string  `xml:"signature,attr"`
+	LineRate   float32 `xml:"line-rate,attr"`
+	BranchRate float32 `xml:"branch-rate,attr"`
+	Complexity float32 `xml:"complexity,attr"`
+	Lines      Lines   `xml:"lines>line"`
+
+	// Statements and StatementsCovered count the statements of the profile
+	// blocks in the method, for StatementMetric.
+	Statements        int64 `xml:"-"`
+	StatementsCovered int64 `xml:"-"`
+}
+
+type Line struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int64  `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+
+	// Branches and BranchesCovered count the outcomes of the decisions on
+	// the line; ConditionCoverage renders them.
+	Branches        int64 `xml:"-"`
+	BranchesCovered int64 `xml:"-"`
+}
+
+// AddBranches records the outcomes of a decision on the line.
+func (line *Line) AddBranches(branches, covered int64) {
+	line.Branch = true
+	line.Branches += branches
+	line.BranchesCovered += covered
+	line.ConditionCoverage = fmt.Sprintf("%d%% (%d/%d)", line.BranchesCovered*100/line.Branches, line.BranchesCovered, line.Branches)
+}
+
+// Lines is a slice of Line pointers, with some convenience methods
+type Lines []*Line
+
+// HitRate returns a float32 from 0.0 to 1.0 representing what fraction of lines
+// have hits; 1.0 if there are none
+func (lines Lines) HitRate() (hitRate float32) {
+	return lineRate(lines.NumLinesWithHits(), lines.NumLines())
+}
+
+// NumLines returns the number of lines
+func (lines Lines) NumLines() int64 {
+	return int64(len(lines))
+}
+
+// NumLinesWithHits returns the number of lines with a hit count > 0
+func (lines Lines) NumLinesWithHits() (numLinesWithHits int64) {
+	for _, line := range lines {
+		if line.Hits > 0 {
+			numLinesWithHits++
+		}
+	}
+	return numLinesWithHits
+}
+
+// BranchHitRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of branches were taken; 1.0 if there are none
+func (lines Lines) BranchHitRate() float32 {
+	return branchRate(lines.NumBranchesCovered(), lines.NumBranches())
+}
+
+// NumBranches returns the number of branches
+func (lines Lines) NumBranches() (numBranches int64) {
+	for _, line := range lines {
+		numBranches += line.Branches
+	}
+	return numBranches
+}
+
+// NumBranchesCovered returns the number of branches that were taken
+func (lines Lines) NumBranchesCovered() (numBranchesCovered int64) {
+	for _, line := range lines {
+		numBranchesCovered += line.BranchesCovered
+	}
+	return numBranchesCovered
+}
+
+// lineRate follows Cobertura in treating code without lines, like an empty
+// function, as fully covered.
+func lineRate(covered, valid int64) float32 {
+	if valid == 0 {
+		return 1
+	}
+	return float32(covered) / float32(valid)
+}
+
+// branchRate follows Cobertura in treating code without branches as fully
+// covered.
+func branchRate(covered, valid int64) float32 {
+	if valid == 0 {
+		return 1
+	}
+	return float32(covered) / float32(valid)
+}
+
+// line returns the line with the given number, or nil.
+func (lines Lines) line(number int) *Line {
+	for _, line := range lines {
+		if line.Number == number {
+			return line
+		}
+	}
+	return nil
+}
+
+// merge adds a copy of line, or merges it into the line with the same number,
+// which then has the higher hit count and the branches of both.
+func (lines *Lines) merge(line *Line) {
+	if l := lines.line(line.Number); l != nil {
+		if line.Hits > l.Hits {
+			l.Hits = line.Hits
+		}
+		if line.Branches > 0 {
+			l.AddBranches(line.Branches, line.BranchesCovered)
+		}
+		return
+	}
+	copied := *line
+	*lines = append(*lines, &copied)
+}
+
+func averageComplexity(sum float32, n int64) float32 {
+	if n == 0 {
+		return 0
+	}
+	return sum / float32(n)
+}
+
+// AddOrUpdateLine adds a line if it is a different line than the last line recorded.
+// If it's the same line as the last line recorded then we update the hits down
+// if the new hits is less; otherwise just leave it as-is
+func (lines *Lines) AddOrUpdateLine(lineNumber int, hits int64) {
+	if len(*lines) > 0 {
+		lastLine := (*lines)[len(*lines)-1]
+		if lineNumber == lastLine.Number {
+			if hits < lastLine.Hits {
+				lastLine.Hits = hits
+			}
+			return
+		}
+	}
+	*lines = append(*lines, &Line{Number: lineNumber, Hits: hits})
+}
+
+// HitRate returns a float32 from 0.0 to 1.0 representing what fraction of lines
+// have hits; 1.0 if there are none
+func (method Method) HitRate() float32 {
+	return method.Lines.HitRate()
+}
+
+// NumLines returns the number of lines
+func (method Method) NumLines() int64 {
+	return method.Lines.NumLines()
+}
+
+// NumLinesWithHits returns the number of lines with a hit count > 0
+func (method Method) NumLinesWithHits() int64 {
+	return method.Lines.NumLinesWithHits()
+}
+
+// BranchHitRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of branches were taken; 1.0 if there are none
+func (method Method) BranchHitRate() float32 {
+	return method.Lines.BranchHitRate()
+}
+
+// NumBranches returns the number of branches
+func (method Method) NumBranches() int64 {
+	return method.Lines.NumBranches()
+}
+
+// NumBranchesCovered returns the number of branches that were taken
+func (method Method) NumBranchesCovered() int64 {
+	return method.Lines.NumBranchesCovered()
+}
+
+// StatementRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of statements were run; 1.0 if there are none
+func (method Method) StatementRate() float32 {
+	return lineRate(method.StatementsCovered, method.Statements)
+}
+
+// NumStatements returns the number of statements
+func (method Method) NumStatements() int64 {
+	return method.Statements
+}
+
+// NumStatementsCovered returns the number of statements that were run
+func (method Method) NumStatementsCovered() int64 {
+	return method.StatementsCovered
+}
+
+// HitRate returns a float32 from 0.0 to 1.0 representing what fraction of lines
+// have hits; 1.0 if there are none
+func (class Class) HitRate() float32 {
+	return lineRate(class.NumLinesWithHits(), class.NumLines())
+}
+
+// NumLines returns the number of lines
+func (class Class) NumLines() int64 {
+	return class.Lines.NumLines()
+}
+
+// NumLinesWithHits returns the number of lines with a hit count > 0
+func (class Class) NumLinesWithHits() int64 {
+	return class.Lines.NumLinesWithHits()
+}
+
+// BranchHitRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of branches were taken; 1.0 if there are none
+func (class Class) BranchHitRate() float32 {
+	return branchRate(class.NumBranchesCovered(), class.NumBranches())
+}
+
+// NumBranches returns the number of branches
+func (class Class) NumBranches() int64 {
+	return class.Lines.NumBranches()
+}
+
+// NumBranchesCovered returns the number of branches that were taken
+func (class Class) NumBranchesCovered() int64 {
+	return class.Lines.NumBranchesCovered()
+}
+
+// StatementRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of statements were run; 1.0 if there are none
+func (class Class) StatementRate() float32 {
+	return lineRate(class.NumStatementsCovered(), class.NumStatements())
+}
+
+// NumStatements returns the number of statements
+func (class Class) NumStatements() (numStatements int64) {
+	for _, method := range class.Methods {
+		numStatements += method.NumStatements()
+	}
+	return numStatements
+}
+
+// NumStatementsCovered returns the number of statements that were run
+func (class Class) NumStatementsCovered() (numStatementsCovered int64) {
+	for _, method := range class.Methods {
+		numStatementsCovered += method.NumStatementsCovered()
+	}
+	return numStatementsCovered
+}
+
+// AverageComplexity returns the mean cyclomatic complexity of the methods,
+// which is how Cobertura reports complexity above the method level
+func (class Class) AverageComplexity() float32 {
+	return averageComplexity(class.SumComplexity(), class.NumMethods())
+}
+
+// NumMethods returns the number of methods
+func (class Class) NumMethods() int64 {
+	return int64(len(class.Methods))
+}
+
+// SumComplexity returns the total cyclomatic complexity of the methods
+func (class Class) SumComplexity() (sum float32) {
+	for _, method := range class.Methods {
+		sum += method.Complexity
+	}
+	return sum
+}
+
+// HitRate returns a float32 from 0.0 to 1.0 representing what fraction of lines
+// have hits; 1.0 if there are none
+func (pkg Package) HitRate() float32 {
+	return lineRate(pkg.NumLinesWithHits(), pkg.NumLines())
+}
+
+// NumLines returns the number of lines
+func (pkg Package) NumLines() (numLines int64) {
+	for _, class := range pkg.Classes {
+		numLines += class.NumLines()
+	}
+	return numLines
+}
+
+// NumLinesWithHits returns the number of lines with a hit count > 0
+func (pkg Package) NumLinesWithHits() (numLinesWithHits int64) {
+	for _, class := range pkg.Classes {
+		numLinesWithHits += class.NumLinesWithHits()
+	}
+	return numLinesWithHits
+}
+
+// BranchHitRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of branches were taken; 1.0 if there are none
+func (pkg Package) BranchHitRate() float32 {
+	return branchRate(pkg.NumBranchesCovered(), pkg.NumBranches())
+}
+
+// NumBranches returns the number of branches
+func (pkg Package) NumBranches() (numBranches int64) {
+	for _, class := range pkg.Classes {
+		numBranches += class.NumBranches()
+	}
+	return numBranches
+}
+
+// NumBranchesCovered returns the number of branches that were taken
+func (pkg Package) NumBranchesCovered() (numBranchesCovered int64) {
+	for _, class := range pkg.Classes {
+		numBranchesCovered += class.NumBranchesCovered()
+	}
+	return numBranchesCovered
+}
+
+// StatementRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of statements were run; 1.0 if there are none
+func (pkg Package) StatementRate() float32 {
+	return lineRate(pkg.NumStatementsCovered(), pkg.NumStatements())
+}
+
+// NumStatements returns the number of statements
+func (pkg Package) NumStatements() (numStatements int64) {
+	for _, class := range pkg.Classes {
+		numStatements += class.NumStatements()
+	}
+	return numStatements
+}
+
+// NumStatementsCovered returns the number of statements that were run
+func (pkg Package) NumStatementsCovered() (numStatementsCovered int64) {
+	for _, class := range pkg.Classes {
+		numStatementsCovered += class.NumStatementsCovered()
+	}
+	return numStatementsCovered
+}
+
+// AverageComplexity returns the mean cyclomatic complexity of the methods,
+// which is how Cobertura reports complexity above the method level
+func (pkg Package) AverageComplexity() float32 {
+	return averageComplexity(pkg.SumComplexity(), pkg.NumMethods())
+}
+
+// NumMethods returns the number of methods
+func (pkg Package) NumMethods() (numMethods int64) {
+	for _, class := range pkg.Classes {
+		numMethods += class.NumMethods()
+	}
+	return numMethods
+}
+
+// SumComplexity returns the total cyclomatic complexity of the methods
+func (pkg Package) SumComplexity() (sum float32) {
+	for _, class := range pkg.Classes {
+		sum += class.SumComplexity()
+	}
+	return sum
+}
+
+// HitRate returns a float32 from 0.0 to 1.0 representing what fraction of lines
+// have hits; 1.0 if there are none
+func (cov Coverage) HitRate() float32 {
+	return lineRate(cov.NumLinesWithHits(), cov.NumLines())
+}
+
+// NumLines returns the number of lines
+func (cov Coverage) NumLines() (numLines int64) {
+	for _, pkg := range cov.Packages {
+		numLines += pkg.NumLines()
+	}
+	return numLines
+}
+
+// NumLinesWithHits returns the number of lines with a hit count > 0
+func (cov Coverage) NumLinesWithHits() (numLinesWithHits int64) {
+	for _, pkg := range cov.Packages {
+		numLinesWithHits += pkg.NumLinesWithHits()
+	}
+	return numLinesWithHits
+}
+
+// BranchHitRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of branches were taken; 1.0 if there are none
+func (cov Coverage) BranchHitRate() float32 {
+	return branchRate(cov.NumBranchesCovered(), cov.NumBranches())
+}
+
+// NumBranches returns the number of branches
+func (cov Coverage) NumBranches() (numBranches int64) {
+	for _, pkg := range cov.Packages {
+		numBranches += pkg.NumBranches()
+	}
+	return numBranches
+}
+
+// NumBranchesCovered returns the number of branches that were taken
+func (cov Coverage) NumBranchesCovered() (numBranchesCovered int64) {
+	for _, pkg := range cov.Packages {
+		numBranchesCovered += pkg.NumBranchesCovered()
+	}
+	return numBranchesCovered
+}
+
+// StatementRate returns a float32 from 0.0 to 1.0 representing what fraction
+// of statements were run; 1.0 if there are none
+func (cov Coverage) StatementRate() float32 {
+	return lineRate(cov.NumStatementsCovered(), cov.NumStatements())
+}
+
+// NumStatements returns the number of statements
+func (cov Coverage) NumStatements() (numStatements int64) {
+	for _, pkg := range cov.Packages {
+		numStatements += pkg.NumStatements()
+	}
+	return numStatements
+}
+
+// NumStatementsCovered returns the number of statements that were run
+func (cov Coverage) NumStatementsCovered() (numStatementsCovered int64) {
+	for _, pkg := range cov.Packages {
+		numStatementsCovered += pkg.NumStatementsCovered()
+	}
+	return numStatementsCovered
+}
+
+// AverageComplexity returns the mean cyclomatic complexity of the methods,
+// which is how Cobertura reports complexity above the method level
+func (cov Coverage) AverageComplexity() float32 {
+	return averageComplexity(cov.SumComplexity(), cov.NumMethods())
+}
+
+// NumMethods returns the number of methods
+func (cov Coverage) NumMethods() (numMethods int64) {
+	for _, pkg := range cov.Packages {
+		numMethods += pkg.NumMethods()
+	}
+	return numMethods
+}
+
+// SumComplexity returns the total cyclomatic complexity of the methods
+func (cov Coverage) SumComplexity() (sum float32) {
+	for _, pkg := range cov.Packages {
+		sum += pkg.SumComplexity()
+	}
+	return sum
+}
@@ -0,0 +1,39 @@
+package cobertura
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// complexity returns the McCabe cyclomatic complexity of a function body: one
+// plus the number of if, for and range statements, non-default case and
+// select clauses and && and || operators. With splitLits the function
+// literals nested in body are left out, as they are methods of their own.
+func complexity(body ast.Node, splitLits bool) int {
+	c := 1
+	if body == nil {
+		return c
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return !splitLits || node == body
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			c++
+		case *ast.CaseClause:
+			if n.List != nil {
+				c++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				c++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				c++
+			}
+		}
+		return true
+	})
+	return c
+}
@@ -0,0 +1,567 @@
+// Package cobertura converts Go coverage profiles to Cobertura XML reports.
+package cobertura
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DocType is the document type declaration written after the XML header.
+const DocType = "<!DOCTYPE coverage SYSTEM \"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n"
+
+// Options controls a conversion. The zero value converts like the command
+// without flags, except that warnings are discarded.
+type Options struct {
+	Timestamp time.Time // report time; the current time if zero, or SOURCE_DATE_EPOCH if Reproducible
+	Strict    bool      // fail with Diagnostics instead of leaving input out
+	Warnings  io.Writer // receives one warning per diagnostic unless Strict
+	Dir       string    // directory to resolve source files from; the working directory if empty
+
+	Signature  SignatureStyle // how Method.Signature is rendered
+	TypeParams TypeParamStyle // how the type parameters of generic receivers are rendered in class names
+	Grouping   ClassGrouping  // which functions share a class
+	FuncClass  FuncClassStyle // the class of functions without receiver
+	Metric     RateMetric     // what line rates measure
+	Thresholds []Threshold    // minimum line rates checked by Convert
+
+	// Include and Exclude select the files to convert by patterns on their
+	// file name or package path, as in the profile after Rewrites. Patterns
+	// are matched as by path.Match, or as regular expressions if prefixed
+	// with "re:". If Include is not empty, only matching files are
+	// converted; files matching Exclude never are.
+	Include, Exclude []string
+	SkipGenerated    bool // leave out files marked "// Code generated ... DO NOT EDIT."
+
+	// ExcludeSymbols leaves out functions matching qualifier.name patterns,
+	// where both parts are globs. The qualifier of a method is the name of
+	// its receiver type without type parameters, "(*T)" matching only pointer
+	// receivers; that of a function is the name of its package. E.g.
+	// "*.String", "(*Mock*).*" or "main.main".
+	ExcludeSymbols []string
+
+	// Relative names files in the report by their path relative to the
+	// first source root containing them, instead of by their name in the
+	// profile, for tools that match them to the files of a repository.
+	Relative bool
+	// Sources are the source roots listed in the report. With Relative they
+	// default to the root of the module or workspace; otherwise to its
+	// module directories or GOPATH.
+	Sources []string
+	// Rewrites are applied to the file names in the profile before they are
+	// resolved, e.g. to map paths of the machine that ran the tests.
+	Rewrites []Rewrite
+
+	// FuncLits reports each function literal as a method of its own, named
+	// like the runtime does, e.g. "Outer.func1" and "Outer.func1.2", and
+	// leaves its lines out of the enclosing method.
+	FuncLits bool
+
+	// Reproducible makes the report depend only on its input: packages,
+	// classes, methods and lines are sorted, rates are rounded to a fixed
+	// precision and the time must come from Timestamp or SOURCE_DATE_EPOCH.
+	Reproducible bool
+}
+
+// Convert reads a coverage profile from in and writes the Cobertura report to
+// out. If the report doesn't meet opts.Thresholds, it returns Violations after
+// writing it. It is safe to call concurrently.
+func Convert(ctx context.Context, in io.Reader, out io.Writer, opts Options) error {
+	profiles, diags, err := ReadProfiles(in, "")
+	if err != nil {
+		return fmt.Errorf("can't parse profiles: %v", err)
+	}
+	cov, err := Build(ctx, profiles, diags, opts)
+	if err != nil {
+		return err
+	}
+	if err := cov.WriteXML(out); err != nil {
+		return err
+	}
+	return cov.CheckThresholds(opts.Thresholds)
+}
+
+// Build returns the Cobertura report for profiles. diags holds the problems
+// already found in the input, e.g. by ReadProfiles; they are treated like the
+// ones found by Build according to opts.Strict.
+func Build(ctx context.Context, profiles []*Profile, diags Diagnostics, opts Options) (*Coverage, error) {
+	c, err := newConverter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	srcDirs := build.Default.SrcDirs()
+	if mods, err := modulesFor(c.dir); err == nil && mods != nil {
+		srcDirs = mods.sourceDirs()
+	}
+	if opts.Relative || len(opts.Sources) > 0 {
+		srcDirs = c.roots
+	}
+	sources := make([]*Source, len(srcDirs))
+	for i, dir := range srcDirs {
+		sources[i] = &Source{dir}
+	}
+
+	ts, err := reportTime(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.cov = &Coverage{Sources: sources, Packages: nil, Timestamp: ts.UnixNano() / int64(time.Millisecond), Metric: opts.Metric}
+	if opts.Metric == StatementMetric {
+		c.cov.Comment = statementComment
+	}
+	more, err := c.parseProfiles(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.report(append(diags, more...)); err != nil {
+		return nil, err
+	}
+	if opts.Reproducible {
+		c.cov.normalize()
+	}
+	return c.cov, nil
+}
+
+// report returns diags if Strict is set and writes them to Warnings otherwise.
+func (opts *Options) report(diags Diagnostics) error {
+	if len(diags) == 0 {
+		return nil
+	}
+	if opts.Strict {
+		return diags
+	}
+	if opts.Warnings != nil {
+		for _, d := range diags {
+			fmt.Fprintf(opts.Warnings, "warning: %v\n", d)
+		}
+	}
+	return nil
+}
+
+// WriteXML writes the report as a Cobertura XML document.
+func (cov *Coverage) WriteXML(out io.Writer) error {
+	fmt.Fprintf(out, xml.Header)
+	fmt.Fprintf(out, DocType)
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "\t")
+	err := encoder.Encode(cov)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out)
+	return err
+}
+
+// converter holds the state of a single conversion.
+type converter struct {
+	opts    Options
+	dir     string
+	filter  *pathFilter
+	symbols []symbolPattern
+	roots   []string          // source roots for Relative
+	classes map[string]*Class // by fileVisitor.classKey
+	cov     *Coverage
+}
+
+func newConverter(opts Options) (*converter, error) {
+	dir := opts.Dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := newPathFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	symbols, err := newSymbolPatterns(opts.ExcludeSymbols)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPatterns(opts.Thresholds); err != nil {
+		return nil, err
+	}
+	return &converter{
+		opts:    opts,
+		dir:     dir,
+		filter:  filter,
+		symbols: symbols,
+		roots:   sourceRoots(opts, dir),
+		classes: make(map[string]*Class),
+		cov:     &Coverage{},
+	}, nil
+}
+
+// parseProfiles adds the profiles selected by the filters to the report, after
+// rewriting their file names, and returns the files that had to be left out.
+// It only fails if ctx is done.
+func (c *converter) parseProfiles(ctx context.Context, profiles []*Profile) (Diagnostics, error) {
+	var diags Diagnostics
+	cov := c.cov
+	cov.Packages = []*Package{}
+	for _, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if name := rewritePath(c.opts.Rewrites, profile.FileName); name != profile.FileName {
+			profile = &Profile{FileName: name, Mode: profile.Mode, Blocks: profile.Blocks}
+		}
+		if !c.filter.match(profile.FileName) {
+			continue
+		}
+		if err := c.parseProfile(profile); err != nil {
+			diags = append(diags, err.(Diagnostic))
+		}
+	}
+	cov.LinesValid = cov.NumLines()
+	cov.LinesCovered = cov.NumLinesWithHits()
+	cov.LineRate = c.opts.Metric.rate(cov)
+	cov.BranchesValid = cov.NumBranches()
+	cov.BranchesCovered = cov.NumBranchesCovered()
+	cov.BranchRate = cov.BranchHitRate()
+	cov.Complexity = cov.AverageComplexity()
+	return diags, nil
+}
+
+// parseProfile adds a profile to the report, unless it is of a generated file
+// and SkipGenerated is set. Errors are of type Diagnostic.
+func (c *converter) parseProfile(profile *Profile) error {
+	cov := c.cov
+	profile = &Profile{
+		FileName: profile.FileName,
+		Mode:     profile.Mode,
+		Blocks:   combineDuplicates(profile.Mode, profile.Blocks),
+	}
+	fileName := profile.FileName
+	absFilePath, err := findFile(c.dir, fileName)
+	if err != nil {
+		return Diagnostic{Kind: UnresolvedFile, File: fileName, Err: err}
+	}
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, absFilePath, nil, parser.ParseComments)
+	if err != nil {
+		return Diagnostic{Kind: UnparseableFile, File: fileName, Err: err}
+	}
+	if c.opts.SkipGenerated && isGenerated(parsed) {
+		return nil
+	}
+	reportName := fileName
+	if c.opts.Relative {
+		if rel, ok := relativeName(c.roots, absFilePath); ok {
+			reportName = rel
+		}
+	}
+
+	pkgPath, _ := filepath.Split(fileName)
+	pkgPath = strings.TrimRight(pkgPath, string(os.PathSeparator))
+
+	var pkg *Package
+	for _, p := range cov.Packages {
+		if p.Name == pkgPath {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		pkg = &Package{Name: pkgPath, Classes: []*Class{}}
+		cov.Packages = append(cov.Packages, pkg)
+	}
+	visitor := &fileVisitor{
+		opts:     &c.opts,
+		fset:     fset,
+		fileName: reportName,
+		classes:  c.classes,
+		pkg:      pkg,
+		profile:  profile,
+		symbols:  c.symbols,
+	}
+	ast.Walk(visitor, parsed)
+	cov.LinesExcluded += visitor.excluded
+	pkg.LineRate = c.opts.Metric.rate(pkg)
+	pkg.BranchRate = pkg.BranchHitRate()
+	pkg.Complexity = pkg.AverageComplexity()
+	return nil
+}
+
+type fileVisitor struct {
+	opts     *Options
+	fset     *token.FileSet
+	fileName string
+	pkg      *Package
+	classes  map[string]*Class
+	profile  *Profile
+	imports  map[string]string
+	pkgName  string
+	symbols  []symbolPattern
+	ignored  map[int]bool     // lines in ignored regions
+	stmts    []token.Position // starts of the statements, sorted
+	excluded int64            // lines left out by directives
+}
+
+func (v *fileVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.File:
+		v.imports = fileImports(n)
+		v.pkgName = n.Name.Name
+		v.ignored = v.ignoredRegions(n)
+		v.stmts = statements(v.fset, n)
+	case *ast.FuncDecl:
+		if v.excludedSymbol(n.Recv, n.Name.Name) {
+			return nil
+		}
+		excluded := v.excluded
+		method, lits := v.method(n)
+		if ignoredFunc(n) || len(method.Lines) == 0 && len(lits) == 0 && v.excluded > excluded {
+			// Left out entirely by directives.
+			return nil
+		}
+		class := v.class(v.className(n))
+		v.addMethod(class, method)
+		v.addFuncLits(class, method.Name, false, lits)
+		return nil
+	case *ast.GenDecl:
+		if n.Tok == token.VAR {
+			v.varMethods(n)
+		}
+		return nil
+	}
+	return v
+}
+
+// method returns the method for n and the function literals in it that are
+// methods of their own.
+func (v *fileVisitor) method(n *ast.FuncDecl) (*Method, []*ast.FuncLit) {
+	method := &Method{Name: n.Name.Name, Signature: v.signature(n), Complexity: float32(complexity(n.Body, v.opts.FuncLits))}
+	var lits []*ast.FuncLit
+	if n.Body != nil && !ignoredFunc(n) {
+		lits = v.funcLits(n.Body)
+	}
+	v.addLines(method, n, lits, ignoredFunc(n))
+	if n.Body != nil {
+		v.addBranches(method, n.Body)
+	}
+	return method, lits
+}
+
+// funcLits returns the function literals in node, but not node itself, that
+// are methods of their own: none unless the FuncLits option is set, and not
+// those nested in other literals.
+func (v *fileVisitor) funcLits(node ast.Node) []*ast.FuncLit {
+	if !v.opts.FuncLits {
+		return nil
+	}
+	var lits []*ast.FuncLit
+	ast.Inspect(node, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok || n == node {
+			return true
+		}
+		lits = append(lits, lit)
+		return false
+	})
+	return lits
+}
+
+// addFuncLits adds methods for the function literals in the method named
+// parent, and for those nested in them, to class. Like the runtime, it names
+// them parent.func1, parent.func2 and so on, and nested ones parent.1,
+// parent.2 and so on. Literals without lines are left out.
+func (v *fileVisitor) addFuncLits(class *Class, parent string, nested bool, lits []*ast.FuncLit) {
+	for i, lit := range lits {
+		name := fmt.Sprintf("%s.func%d", parent, i+1)
+		if nested {
+			name = fmt.Sprintf("%s.%d", parent, i+1)
+		}
+		method := &Method{Name: name, Signature: v.litSignature(lit), Complexity: float32(complexity(lit, true))}
+		inner := v.funcLits(lit)
+		v.addLines(method, lit, inner, false)
+		if len(method.Lines) > 0 {
+			v.addBranches(method, lit)
+			v.addMethod(class, method)
+		}
+		v.addFuncLits(class, name, true, inner)
+	}
+}
+
+// varMethods adds pseudo methods for the profile blocks in the initializers
+// of package-level variables, e.g. in the function literals of a routing
+// table. They are named after the variable, or "init" for the blank
+// identifier, and belong to the class of functions.
+func (v *fileVisitor) varMethods(n *ast.GenDecl) {
+	for _, spec := range n.Specs {
+		vs := spec.(*ast.ValueSpec)
+		for i, value := range vs.Values {
+			ident := vs.Names[0].Name
+			if len(vs.Names) == len(vs.Values) {
+				ident = vs.Names[i].Name
+			}
+			name := ident
+			if name == "_" {
+				name = "init"
+			}
+			if v.excludedSymbol(nil, name) {
+				continue
+			}
+			method := &Method{Name: name, Signature: v.varSignature(ident, vs.Type), Complexity: float32(complexity(value, v.opts.FuncLits))}
+			lits := v.funcLits(value)
+			v.addLines(method, value, lits, false)
+			class := v.class(v.className(nil))
+			if len(method.Lines) > 0 {
+				v.addBranches(method, value)
+				v.addMethod(class, method)
+			}
+			v.addFuncLits(class, name, false, lits)
+		}
+	}
+}
+
+// addLines adds the lines of the profile blocks within node to method, unless
+// they start in one of lits or are ignored by directives.
+func (v *fileVisitor) addLines(method *Method, node ast.Node, lits []*ast.FuncLit, ignoreAll bool) {
+	method.Lines = []*Line{}
+
+	start := v.fset.Position(node.Pos())
+	end := v.fset.Position(node.End())
+	startLine := start.Line
+	startCol := start.Column
+	endLine := end.Line
+	endCol := end.Column
+	excluded := make(map[int]bool)
+	// The blocks are sorted, so we can stop counting as soon as we reach the end of the relevant block.
+	for _, b := range v.profile.Blocks {
+		if b.StartLine > endLine || (b.StartLine == endLine && b.StartCol >= endCol) {
+			// Past the end of the function.
+			break
+		}
+		if after(startLine, startCol, b.StartLine, b.StartCol) {
+			// Starts before the beginning of the function, e.g. the block
+			// of the statement holding a function literal.
+			continue
+		}
+		if v.inFuncLit(b, lits) {
+			continue
+		}
+		kept := false
+		for _, i := range v.blockLines(b) {
+			if ignoreAll || v.ignored[i] {
+				excluded[i] = true
+				continue
+			}
+			method.Lines.AddOrUpdateLine(i, int64(b.Count))
+			kept = true
+		}
+		if kept {
+			method.Statements += int64(b.NumStmt)
+			if b.Count > 0 {
+				method.StatementsCovered += int64(b.NumStmt)
+			}
+		}
+	}
+	v.excluded += int64(len(excluded))
+}
+
+// inFuncLit reports whether b starts in one of lits.
+func (v *fileVisitor) inFuncLit(b ProfileBlock, lits []*ast.FuncLit) bool {
+	for _, lit := range lits {
+		start, end := v.fset.Position(lit.Pos()), v.fset.Position(lit.End())
+		if !after(start.Line, start.Column, b.StartLine, b.StartCol) && after(end.Line, end.Column, b.StartLine, b.StartCol) {
+			return true
+		}
+	}
+	return false
+}
+
+// addBranches adds the decisions in body to the lines of method.
+func (v *fileVisitor) addBranches(method *Method, body ast.Node) {
+	for _, d := range v.decisions(body) {
+		if line := method.Lines.line(d.Line); line != nil {
+			line.AddBranches(int64(d.Branches), int64(d.Covered))
+		}
+	}
+}
+
+// addMethod adds method and its lines to class and updates the rates.
+func (v *fileVisitor) addMethod(class *Class, method *Method) {
+	method.LineRate = v.opts.Metric.rate(method)
+	method.BranchRate = method.Lines.BranchHitRate()
+	class.Methods = append(class.Methods, method)
+	for _, line := range method.Lines {
+		// Function literals share lines with the enclosing method.
+		class.Lines.merge(line)
+	}
+	class.LineRate = v.opts.Metric.rate(class)
+	class.BranchRate = class.Lines.BranchHitRate()
+	class.Complexity = class.AverageComplexity()
+}
+
+func (v *fileVisitor) class(className string, typed bool) *Class {
+	className, key := v.classKey(className)
+	var class *Class = v.classes[key]
+	if class == nil {
+		class = &Class{Name: className, Filename: v.fileName, Methods: []*Method{}, Lines: []*Line{}}
+		v.classes[key] = class
+		v.addClass(class, typed)
+	}
+	return class
+}
+
+// recvName returns the class name for a receiver. The receiver type is
+// resolved structurally, so spacing, pointers and the names of type
+// parameters don't matter; type parameters are rendered as configured.
+func (v *fileVisitor) recvName(recv *ast.FieldList) string {
+	typ, _, generic := recvType(recv)
+	return v.typeName(typ, generic)
+}
+
+// typeName renders the class name of a named type, with or without type
+// parameters.
+func (v *fileVisitor) typeName(typ ast.Expr, generic bool) string {
+	name := types.ExprString(typ)
+	if generic && v.opts.TypeParams == RuntimeTypeParams {
+		name += "[...]"
+	}
+	return name
+}
+
+// recvType returns the named type of a receiver, without pointer, parentheses
+// and type parameters, and whether it had a pointer or type parameters.
+func recvType(recv *ast.FieldList) (typ ast.Expr, pointer, generic bool) {
+	return namedType(recv.List[0].Type)
+}
+
+// namedType is like recvType for any type expression.
+func namedType(typ ast.Expr) (named ast.Expr, pointer, generic bool) {
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ, pointer = t.X, true
+			continue
+		case *ast.ParenExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			return t.X, pointer, true
+		case *ast.IndexListExpr:
+			return t.X, pointer, true
+		}
+		return typ, pointer, false
+	}
+}
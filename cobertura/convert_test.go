@@ -0,0 +1,603 @@
+package cobertura
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const SaveTestResults = false
+
+type dirInfo struct {
+	PkgPath string
+}
+
+func TestConvertParseProfilesError(t *testing.T) {
+	err := Convert(context.Background(), strings.NewReader("invalid data"), ioutil.Discard, Options{})
+	if err == nil || !strings.Contains(err.Error(), "bad mode line") {
+		t.Errorf("Expected \"bad mode line\" error; got: %+v", err)
+	}
+}
+
+func TestConvertOutputError(t *testing.T) {
+	pipe2rd, pipe2wr := io.Pipe()
+	pipe2wr.Close()
+	defer func() { pipe2rd.Close() }()
+	err := Convert(context.Background(), strings.NewReader("mode: set"), pipe2wr, Options{})
+	if err == nil || err.Error() != "io: read/write on closed pipe" {
+		t.Errorf("Expected closed pipe error; got: %+v", err)
+	}
+}
+
+func TestConvertEmpty(t *testing.T) {
+	data := `mode: set`
+
+	pipe2rd, pipe2wr := io.Pipe()
+	go Convert(context.Background(), strings.NewReader(data), pipe2wr, Options{})
+
+	v := Coverage{}
+	dec := xml.NewDecoder(pipe2rd)
+	dec.Decode(&v)
+
+	if v.XMLName.Local != "coverage" {
+		t.Error()
+	}
+	if v.Sources == nil {
+		t.Fatal()
+	}
+	if v.Packages != nil {
+		t.Fatal()
+	}
+}
+
+func TestParseProfileDoesntExist(t *testing.T) {
+	c, _ := newConverter(Options{})
+	profile := Profile{FileName: "does-not-exist"}
+	err := c.parseProfile(&profile)
+	if err == nil || !strings.Contains(err.Error(), `can't find "does-not-exist"`) {
+		t.Fatalf("Expected \"can't find\" error; got: %+v", err)
+	}
+}
+
+func TestParseProfileNotReadable(t *testing.T) {
+	c, _ := newConverter(Options{})
+	profile := Profile{FileName: os.DevNull}
+	err := c.parseProfile(&profile)
+	if err == nil || !strings.Contains(err.Error(), `expected 'package', found 'EOF'`) {
+		t.Fatalf("Expected \"expected 'package', found 'EOF'\" error; got: %+v", err)
+	}
+}
+
+func TestParseProfilePermissionDenied(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "not-readable")
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Chmod(000)
+	c, _ := newConverter(Options{})
+	profile := Profile{FileName: tmpfile.Name()}
+	err = c.parseProfile(&profile)
+	if err == nil || !strings.Contains(err.Error(), `permission denied`) {
+		t.Fatalf("Expected \"permission denied\" error; got: %+v", err)
+	}
+}
+
+func TestConvertSetMode(t *testing.T) {
+	pipe1rd, err := os.Open("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal("Can't parse testdata.")
+	}
+
+	pipe2rd, pipe2wr := io.Pipe()
+
+	var convwr io.Writer = pipe2wr
+	if SaveTestResults {
+		testwr, err := os.Create("testdata/testdata_set.xml")
+		if err != nil {
+			t.Fatal("Can't open output testdata.", err)
+		}
+		defer testwr.Close()
+		convwr = io.MultiWriter(convwr, testwr)
+	}
+
+	go Convert(context.Background(), pipe1rd, convwr, Options{})
+
+	v := Coverage{}
+	dec := xml.NewDecoder(pipe2rd)
+	dec.Decode(&v)
+
+	if v.XMLName.Local != "coverage" {
+		t.Error()
+	}
+
+	if v.Sources == nil {
+		t.Fatal()
+	}
+
+	if v.Packages == nil || len(v.Packages) != 1 {
+		t.Fatal()
+	}
+
+	p := v.Packages[0]
+	if strings.TrimRight(p.Name, "/") != "./testdata" {
+		t.Fatal(p.Name)
+	}
+	if p.Classes == nil || len(p.Classes) != 2 {
+		t.Fatal()
+	}
+
+	c := p.Classes[0]
+	if c.Name != "-" {
+		t.Error()
+	}
+	if c.Filename != "./testdata/func1.go" {
+		t.Errorf("Expected %s but %s", "./testdata/func1.go", c.Filename)
+	}
+	if c.Methods == nil || len(c.Methods) != 1 {
+		t.Fatal()
+	}
+	// Only the lines where statements start: the if and its body.
+	if c.Lines == nil || len(c.Lines) != 2 {
+		t.Errorf("Expected 2 lines but got %d", len(c.Lines))
+	}
+
+	m := c.Methods[0]
+	if m.Name != "Func1" {
+		t.Error()
+	}
+	if m.Lines == nil || len(m.Lines) != 2 {
+		t.Fatalf("Expected 2 lines but got %d", len(m.Lines))
+	}
+
+	var l *Line
+	if l = m.Lines[0]; l.Number != 5 || l.Hits != 1 {
+		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	}
+	if l = m.Lines[1]; l.Number != 6 || l.Hits != 0 {
+		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	}
+
+	if l = c.Lines[0]; l.Number != 5 || l.Hits != 1 {
+		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	}
+	if l = c.Lines[1]; l.Number != 6 || l.Hits != 0 {
+		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	}
+
+	c = p.Classes[1]
+	if c.Name != "Type1" {
+		t.Error()
+	}
+	if c.Filename != "./testdata/func2.go" {
+		t.Errorf("Expected %s but %s", "./testdata/func2.go", c.Filename)
+	}
+	if c.Methods == nil || len(c.Methods) != 3 {
+		t.Fatal()
+	}
+	// Func2b has no statements, so Cobertura counts it as covered.
+	if m = c.Methods[1]; len(m.Lines) != 0 || m.LineRate != 1 {
+		t.Errorf("Expected no lines and line rate 1 for %s; got %d lines and rate %v", m.Name, len(m.Lines), m.LineRate)
+	}
+}
+
+func TestParseProfilesMalformedLine(t *testing.T) {
+	data := "mode: set\n./testdata/func1.go:4.23,5.16 1 1\nnot a block\n./testdata/func1.go:5.16,7.3 1 99999999999999999999\n"
+	_, err := ParseProfiles(strings.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "line 3: malformed profile line") {
+		t.Fatalf("Expected malformed line error; got: %+v", err)
+	}
+
+	var diags Diagnostics
+	profiles, err := parseProfiles(strings.NewReader(data), "cover.out", &diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || len(profiles[0].Blocks) != 1 {
+		t.Errorf("Expected the valid block only; got %+v", profiles)
+	}
+	if len(diags) != 2 || diags[0].Line != 3 || diags[1].Line != 4 || diags[1].Kind != MalformedLine {
+		t.Fatalf("Unexpected diagnostics: %v", diags)
+	}
+	if !strings.HasPrefix(diags[0].Error(), "cover.out:3: malformed profile line: ") {
+		t.Errorf("Unexpected diagnostic message: %s", diags[0].Error())
+	}
+}
+
+func TestBuildDiagnostics(t *testing.T) {
+	profiles := []*Profile{
+		{FileName: "does-not-exist.go", Mode: "set"},
+		{FileName: os.DevNull, Mode: "set"},
+	}
+	diags := Diagnostics{{Kind: MalformedLine, File: "cover.out", Line: 2}}
+
+	_, err := Build(context.Background(), profiles, diags, Options{Strict: true})
+	ds, ok := err.(Diagnostics)
+	if !ok || len(ds) != 3 {
+		t.Fatalf("Expected 3 diagnostics; got: %+v", err)
+	}
+	if ds[1].Kind != UnresolvedFile || ds[1].File != "does-not-exist.go" {
+		t.Errorf("Unexpected diagnostic: %v", ds[1])
+	}
+	if ds[2].Kind != UnparseableFile || ds[2].File != os.DevNull {
+		t.Errorf("Unexpected diagnostic: %v", ds[2])
+	}
+
+	var warnings strings.Builder
+	_, err = Build(context.Background(), profiles, diags, Options{Warnings: &warnings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(warnings.String(), "warning: "); n != 3 {
+		t.Errorf("Expected 3 warnings; got:\n%s", warnings.String())
+	}
+}
+
+func TestConvertConcurrent(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		out string
+		err error
+	}
+	results := make(chan result)
+	for i := 0; i < 8; i++ {
+		go func() {
+			var out strings.Builder
+			err := Convert(context.Background(), strings.NewReader(string(data)), &out, Options{Dir: dir, Strict: true})
+			results <- result{out.String(), err}
+		}()
+	}
+	var first string
+	for i := 0; i < 8; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		// Timestamps may differ; compare everything from the sources on.
+		out := r.out[strings.Index(r.out, "<sources>"):]
+		if i == 0 {
+			first = out
+		} else if out != first {
+			t.Errorf("concurrent conversions differ")
+		}
+	}
+}
+
+func TestConvertCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f, err := os.Open("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := Convert(ctx, f, ioutil.Discard, Options{}); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled; got: %v", err)
+	}
+}
+
+// buildTestdata converts a profile in testdata with opts.
+func buildTestdata(t *testing.T, profile string, opts Options) *Coverage {
+	f, err := os.Open(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	profiles, diags, err := ReadProfiles(f, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Strict = true
+	cov, err := Build(context.Background(), profiles, diags, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cov
+}
+
+func TestBranchCoverage(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_branches.txt", Options{})
+	m := cov.Packages[0].Classes[0].Methods[0]
+	want := map[int]string{
+		4:  "50% (2/4)",
+		6:  "0% (0/2)",
+		11: "33% (1/3)",
+		18: "100% (2/2)",
+		21: "50% (1/2)",
+	}
+	for _, l := range m.Lines {
+		if l.Branch != (want[l.Number] != "") || l.ConditionCoverage != want[l.Number] {
+			t.Errorf("line %d: branch=%v condition-coverage=%q; want %q", l.Number, l.Branch, l.ConditionCoverage, want[l.Number])
+		}
+	}
+	if cov.BranchesValid != 13 || cov.BranchesCovered != 6 {
+		t.Errorf("Expected 6/13 branches; got %d/%d", cov.BranchesCovered, cov.BranchesValid)
+	}
+	if m.BranchRate != float32(6)/13 || cov.Packages[0].BranchRate != m.BranchRate || cov.BranchRate != m.BranchRate {
+		t.Errorf("Unexpected branch rates: method %v, package %v, root %v", m.BranchRate, cov.Packages[0].BranchRate, cov.BranchRate)
+	}
+}
+
+func TestBranchCoverageSetMode(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_set.txt", Options{})
+	classes := cov.Packages[0].Classes
+	if l := classes[0].Methods[0].Lines.line(5); l == nil || l.ConditionCoverage != "50% (1/2)" {
+		t.Errorf("Func1: unexpected condition coverage on line 5: %+v", l)
+	}
+	if l := classes[1].Methods[0].Lines.line(8); l == nil || l.ConditionCoverage != "50% (1/2)" {
+		t.Errorf("Func2a: unexpected condition coverage on line 8: %+v", l)
+	}
+	if m := classes[1].Methods[1]; m.BranchRate != 1 {
+		t.Errorf("Func2b: expected branch-rate 1 without branches; got %v", m.BranchRate)
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_branches.txt", Options{})
+	// 1 + if + && + else if + 2 cases + for + 1 comm clause
+	if m := cov.Packages[0].Classes[0].Methods[0]; m.Complexity != 8 {
+		t.Errorf("Expected complexity 8; got %v", m.Complexity)
+	}
+
+	cov = buildTestdata(t, "testdata/testdata_set.txt", Options{})
+	classes := cov.Packages[0].Classes
+	if c := classes[0]; c.Methods[0].Complexity != 2 || c.Complexity != 2 {
+		t.Errorf("Func1: expected complexity 2; got method %v, class %v", c.Methods[0].Complexity, c.Complexity)
+	}
+	// Func2a has an if, Func2b and Func2c are empty.
+	if c := classes[1]; c.Complexity != float32(4)/3 {
+		t.Errorf("Type1: expected average complexity 4/3; got %v", c.Complexity)
+	}
+	if p := cov.Packages[0]; p.Complexity != 1.5 || cov.Complexity != 1.5 {
+		t.Errorf("Expected average complexity 1.5; got package %v, root %v", p.Complexity, cov.Complexity)
+	}
+}
+
+func TestMethodSignature(t *testing.T) {
+	tests := []struct {
+		style SignatureStyle
+		want  []string
+	}{
+		{GoSignature, []string{
+			"func (l *List[T]) Push(v T, more ...T) error",
+			"func Map[K comparable, V any](m map[K]V, w io.Writer) (n int, err error)",
+			"func Join(a, b string, r *str.Reader) string",
+		}},
+		{JVMSignature, []string{
+			"(Ljava/lang/Object;[Ljava/lang/Object;)Ljava/lang/Throwable;",
+			"(Ljava/util/Map;Lio/Writer;)[Ljava/lang/Object;",
+			"(Ljava/lang/String;Ljava/lang/String;Lstrings/Reader;)Ljava/lang/String;",
+		}},
+	}
+	for _, tt := range tests {
+		cov := buildTestdata(t, "testdata/testdata_signatures.txt", Options{Signature: tt.style})
+		var got []string
+		for _, c := range cov.Packages[0].Classes {
+			for _, m := range c.Methods {
+				got = append(got, m.Signature)
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%v signatures:\n%s\nwant:\n%s", tt.style, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestBuildDuplicateBlocks(t *testing.T) {
+	profiles := []*Profile{{
+		FileName: "./testdata/func1.go",
+		Mode:     "set",
+		Blocks: []ProfileBlock{
+			{StartLine: 4, StartCol: 23, EndLine: 5, EndCol: 16, NumStmt: 1, Count: 1},
+			{StartLine: 5, StartCol: 16, EndLine: 7, EndCol: 3, NumStmt: 1, Count: 0},
+			{StartLine: 4, StartCol: 23, EndLine: 5, EndCol: 16, NumStmt: 1, Count: 0},
+			{StartLine: 5, StartCol: 16, EndLine: 7, EndCol: 3, NumStmt: 1, Count: 1},
+		},
+	}}
+	cov, err := Build(context.Background(), profiles, nil, Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cov.LinesCovered != cov.LinesValid {
+		t.Errorf("Expected all lines covered by one of the duplicates; got %d/%d", cov.LinesCovered, cov.LinesValid)
+	}
+	if len(profiles[0].Blocks) != 4 {
+		t.Errorf("Build modified its input")
+	}
+}
+
+func TestVarInitializers(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_vars.txt", Options{})
+	classes := cov.Packages[0].Classes
+	if len(classes) != 1 || classes[0].Name != "-" {
+		t.Fatalf("Expected only the class of functions; got %+v", classes)
+	}
+	want := []struct {
+		name, signature string
+		lines           []int
+	}{
+		{"Upper", "var Upper", []int{11}},
+		{"routes", "var routes", []int{15, 17, 18, 20}},
+		{"init", "var _", []int{25}},
+		{"register", "func register(f func()) bool", []int{31, 32}},
+		{"Route", "func Route(path, s string) string", []int{36, 37, 38, 41}},
+	}
+	methods := classes[0].Methods
+	if len(methods) != len(want) {
+		t.Fatalf("Got %d methods; want %d", len(methods), len(want))
+	}
+	for i, w := range want {
+		m := methods[i]
+		var lines []int
+		for _, l := range m.Lines {
+			lines = append(lines, l.Number)
+		}
+		if m.Name != w.name || m.Signature != w.signature || fmt.Sprint(lines) != fmt.Sprint(w.lines) {
+			t.Errorf("Method %d: got %s %q %v; want %s %q %v", i, m.Name, m.Signature, lines, w.name, w.signature, w.lines)
+		}
+	}
+	if line := methods[1].Lines.line(17); line == nil || line.ConditionCoverage != "50% (1/2)" {
+		t.Errorf("Expected the branches of the if in the routing table; got %+v", line)
+	}
+	if cov.LinesValid != 12 {
+		t.Errorf("Got %d valid lines; want the 12 statement lines of the profile", cov.LinesValid)
+	}
+}
+
+func TestFuncLits(t *testing.T) {
+	type method struct {
+		name, signature string
+		lines           string
+		complexity      float32
+	}
+	methods := func(cov *Coverage) []method {
+		var ms []method
+		for _, m := range cov.Packages[0].Classes[0].Methods {
+			var lines []int
+			for _, l := range m.Lines {
+				lines = append(lines, l.Number)
+			}
+			ms = append(ms, method{m.Name, m.Signature, fmt.Sprint(lines), m.Complexity})
+		}
+		return ms
+	}
+
+	cov := buildTestdata(t, "testdata/testdata_funclits.txt", Options{})
+	want := []method{{"Sorted", "func Sorted(xs []int, done chan bool) []int", "[6 7 8 9 11 12 15]", 2}}
+	if got := methods(cov); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Got %v; want %v", got, want)
+	}
+
+	cov = buildTestdata(t, "testdata/testdata_funclits.txt", Options{FuncLits: true})
+	want = []method{
+		{"Sorted", "func Sorted(xs []int, done chan bool) []int", "[6 7 15]", 1},
+		{"Sorted.func1", "func(i, j int) bool", "[6]", 1},
+		{"Sorted.func2", "func()", "[8 11 12]", 2},
+		{"Sorted.func2.1", "func()", "[9]", 1},
+	}
+	if got := methods(cov); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Got %v; want %v", got, want)
+	}
+	if line := cov.Packages[0].Classes[0].Methods[2].Lines.line(11); line == nil || line.ConditionCoverage != "50% (1/2)" {
+		t.Errorf("Expected the branches of the if in the goroutine; got %+v", line)
+	}
+	if cov.LinesValid != 7 {
+		t.Errorf("Got %d valid lines; want 7 with line 6 of Sorted and Sorted.func1 counted once", cov.LinesValid)
+	}
+	var lines []int
+	for _, l := range cov.Packages[0].Classes[0].Lines {
+		lines = append(lines, l.Number)
+	}
+	if got := fmt.Sprint(lines); got != "[6 7 15 8 11 12 9]" {
+		t.Errorf("Got class lines %s; want each line once", got)
+	}
+}
+
+func TestGenericReceivers(t *testing.T) {
+	tests := []struct {
+		style   TypeParamStyle
+		classes string
+	}{
+		{RuntimeTypeParams, "[List[...]:Push,Len,Reset Pair[...]:Key Plain:Name]"},
+		{BareTypeParams, "[List:Push,Len,Reset Pair:Key Plain:Name]"},
+	}
+	for _, tt := range tests {
+		cov := buildTestdata(t, "testdata/testdata_generics.txt", Options{TypeParams: tt.style})
+		if got := classNames(cov.Packages[0]); got != tt.classes {
+			t.Errorf("%v: got classes %s; want %s", tt.style, got, tt.classes)
+		}
+	}
+}
+
+// classNames renders the classes of pkg with their methods, e.g.
+// "[T:A,B -:F]".
+func classNames(pkg *Package) string {
+	var classes []string
+	for _, class := range pkg.Classes {
+		var methods []string
+		for _, m := range class.Methods {
+			methods = append(methods, m.Name)
+		}
+		classes = append(classes, class.Name+":"+strings.Join(methods, ","))
+	}
+	return fmt.Sprint(classes)
+}
+
+func TestClassGrouping(t *testing.T) {
+	var profiles []*Profile
+	for _, name := range []string{"testdata/testdata_generics.txt", "testdata/testdata_signatures.txt", "testdata/testdata_set.txt"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps, _, err := ReadProfiles(f, name)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		profiles = append(profiles, ps...)
+	}
+	tests := []struct {
+		grouping ClassGrouping
+		classes  string
+		files    string // file and line numbers of each class
+	}{
+		{ReceiverClasses, "[List[...]:Push,Len,Reset Pair[...]:Key Plain:Name List[...]:Push -:Map,Join -:Func1 Type1:Func2a,Func2b,Func2c]",
+			"[generics.go:8,12,16 generics.go:25 generics.go:31 signatures.go:13,14,15 signatures.go:19,23 func1.go:5,6 func2.go:8,9]"},
+		{FileClasses, "[generics.go:Push,Len,Reset,Key,Name signatures.go:Push,Map,Join func1.go:Func1 func2.go:Func2a,Func2b,Func2c]",
+			"[generics.go:8,12,16,25,31 signatures.go:13,14,15,19,23 func1.go:5,6 func2.go:8,9]"},
+		// The classes of List in both files are listed together.
+		{TypeClasses, "[List[...]:Push,Len,Reset List[...]:Push Pair[...]:Key Plain:Name -:Map,Join -:Func1 Type1:Func2a,Func2b,Func2c]",
+			"[generics.go:8,12,16 signatures.go:13,14,15 generics.go:25 generics.go:31 signatures.go:19,23 func1.go:5,6 func2.go:8,9]"},
+	}
+	for _, tt := range tests {
+		cov, err := Build(context.Background(), profiles, nil, Options{Strict: true, Grouping: tt.grouping})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cov.Packages) != 1 {
+			t.Fatalf("%v: got %d packages; want 1", tt.grouping, len(cov.Packages))
+		}
+		if got := classNames(cov.Packages[0]); got != tt.classes {
+			t.Errorf("%v: got classes %s; want %s", tt.grouping, got, tt.classes)
+		}
+		var files []string
+		for _, class := range cov.Packages[0].Classes {
+			var lines []string
+			for _, line := range class.Lines {
+				lines = append(lines, fmt.Sprint(line.Number))
+			}
+			files = append(files, filepath.Base(class.Filename)+":"+strings.Join(lines, ","))
+		}
+		if got := fmt.Sprint(files); got != tt.files {
+			t.Errorf("%v: got files %s; want %s", tt.grouping, got, tt.files)
+		}
+	}
+}
+
+func TestFuncClasses(t *testing.T) {
+	tests := []struct {
+		style   FuncClassStyle
+		classes string
+	}{
+		{DashFuncClass, "[-:NewServer,newServerWithOpts,NewBox,New,newline Server:Addr]"},
+		{FileFuncClass, "[constructors.go:NewServer,newServerWithOpts,NewBox,New,newline Server:Addr]"},
+		{ConstructorFuncClass, "[Server:NewServer,newServerWithOpts,Addr Box[...]:NewBox constructors.go:New,newline]"},
+	}
+	for _, tt := range tests {
+		cov := buildTestdata(t, "testdata/testdata_constructors.txt", Options{FuncClass: tt.style})
+		if got := classNames(cov.Packages[0]); got != tt.classes {
+			t.Errorf("%v: got classes %s; want %s", tt.style, got, tt.classes)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package cobertura
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The binary coverage data format written by programs built with
+// "go build -cover" (Go 1.20 and later) to the GOCOVERDIR directory. See
+// internal/coverage in the Go distribution for the authoritative definitions.
+const (
+	covMetaPrefix    = "covmeta."
+	covCounterPrefix = "covcounters."
+
+	covMetaFileHeaderSize    = 56 // MetaFileHeader
+	covMetaSymbolHeaderSize  = 44 // MetaSymbolHeader
+	covCounterFileHeaderSize = 32 // CounterFileHeader
+	covCounterFooterSize     = 16 // CounterFileFooter
+
+	covCounterRaw     = 1 // counters are uint32 in the file's byte order
+	covCounterULEB128 = 2 // counters are ULEB128 encoded
+
+	covGranularityPerFunc = 2
+)
+
+var (
+	covMetaMagic    = []byte{0x00, 0x63, 0x76, 0x6d}
+	covCounterMagic = []byte{0x00, 0x63, 0x77, 0x6d}
+	covModes        = map[byte]string{1: "set", 2: "count", 3: "atomic"}
+)
+
+// covMetaFile is a decoded covmeta file: the coverable units of every
+// function of every package of one program.
+type covMetaFile struct {
+	Mode     string
+	PerFunc  bool
+	Packages [][]covFunc
+}
+
+// covFunc is a function and its coverable units, which become profile blocks.
+type covFunc struct {
+	File  string
+	Units []ProfileBlock
+}
+
+// ReadCoverDir reads the covmeta and covcounters files that programs built
+// with "go build -cover" write to GOCOVERDIR and returns them as profiles, as
+// "go tool covdata textfmt" would. Counters of all runs are merged like the
+// blocks of MergeProfiles.
+func ReadCoverDir(dir string) ([]*Profile, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	metas := make(map[string]*covMetaFile)
+	var counterFiles []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case e.IsDir():
+		case strings.HasPrefix(name, covMetaPrefix):
+			meta, err := readCovMeta(filepath.Join(dir, name))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			metas[strings.TrimPrefix(name, covMetaPrefix)] = meta
+		case strings.HasPrefix(name, covCounterPrefix):
+			counterFiles = append(counterFiles, name)
+		}
+	}
+	if len(metas) == 0 {
+		return nil, fmt.Errorf("%s: no %s* files", dir, covMetaPrefix)
+	}
+
+	counters := make(map[string][][][]uint32)
+	for _, name := range counterFiles {
+		if err := readCovCounters(filepath.Join(dir, name), metas, counters); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	hashes := make([]string, 0, len(metas))
+	for hash := range metas {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	var profiles []*Profile
+	for _, hash := range hashes {
+		profiles = MergeProfiles(profiles, metas[hash].profiles(counters[hash]))
+	}
+	return profiles, nil
+}
+
+// profiles returns the profile blocks of meta with the given counters, indexed
+// by package, function and counter.
+func (meta *covMetaFile) profiles(counters [][][]uint32) []*Profile {
+	files := make(map[string]*Profile)
+	for pkgIdx, funcs := range meta.Packages {
+		for funcIdx, fn := range funcs {
+			var ctrs []uint32
+			if pkgIdx < len(counters) && funcIdx < len(counters[pkgIdx]) {
+				ctrs = counters[pkgIdx][funcIdx]
+			}
+			p := files[fn.File]
+			if p == nil {
+				p = &Profile{FileName: fn.File, Mode: meta.Mode}
+				files[fn.File] = p
+			}
+			for i, b := range fn.Units {
+				ctr := i
+				if meta.PerFunc {
+					ctr = 0
+				}
+				if ctr < len(ctrs) {
+					b.Count = int(ctrs[ctr])
+				}
+				p.Blocks = append(p.Blocks, b)
+			}
+		}
+	}
+	return sortedProfiles(files)
+}
+
+// readCovMeta decodes a covmeta file.
+func readCovMeta(file string) (*covMetaFile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r := &covReader{data: data, order: binary.LittleEndian}
+	if !bytes.Equal(r.bytes(4), covMetaMagic) {
+		return nil, errors.New("not a coverage meta-data file")
+	}
+	if v := r.uint32(); v != 1 {
+		return nil, fmt.Errorf("unsupported meta-data file version %d", v)
+	}
+	r.seek(16)
+	entries := r.uint64()
+	r.seek(48)
+	mode, granularity := r.byte(), r.byte()
+	meta := &covMetaFile{Mode: covModes[mode], PerFunc: granularity == covGranularityPerFunc}
+	if meta.Mode == "" {
+		return nil, fmt.Errorf("unsupported counter mode %d", mode)
+	}
+
+	r.seek(covMetaFileHeaderSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if entries > uint64(len(data)-covMetaFileHeaderSize)/16 {
+		return nil, fmt.Errorf("package count %d out of bounds", entries)
+	}
+	offsets := make([]uint64, entries)
+	lengths := make([]uint64, entries)
+	for i := range offsets {
+		offsets[i] = r.uint64()
+	}
+	for i := range lengths {
+		lengths[i] = r.uint64()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	for i := range offsets {
+		if offsets[i] > uint64(len(data)) || lengths[i] > uint64(len(data))-offsets[i] {
+			return nil, fmt.Errorf("package %d out of bounds", i)
+		}
+		funcs, err := readCovPackage(data[offsets[i] : offsets[i]+lengths[i]])
+		if err != nil {
+			return nil, fmt.Errorf("package %d: %v", i, err)
+		}
+		meta.Packages = append(meta.Packages, funcs)
+	}
+	return meta, nil
+}
+
+// readCovPackage decodes the meta-data of one package.
+func readCovPackage(data []byte) ([]covFunc, error) {
+	r := &covReader{data: data, order: binary.LittleEndian}
+	r.seek(40)
+	numFuncs := uint64(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if numFuncs > uint64(len(data)-covMetaSymbolHeaderSize)/4 {
+		return nil, fmt.Errorf("function count %d out of bounds", numFuncs)
+	}
+	r.seek(covMetaSymbolHeaderSize + 4*int(numFuncs))
+	strs := r.stringTable()
+
+	funcs := make([]covFunc, numFuncs)
+	for i := range funcs {
+		r.seek(covMetaSymbolHeaderSize + 4*i)
+		r.seek(int(r.uint32()))
+		numUnits := r.uleb()
+		r.uleb() // function name
+		file := r.uleb()
+		if r.err != nil {
+			return nil, r.err
+		}
+		if file >= uint64(len(strs)) {
+			return nil, fmt.Errorf("function %d: bad file name index", i)
+		}
+		fn := covFunc{File: strs[file]}
+		for u := uint64(0); u < numUnits && r.err == nil; u++ {
+			fn.Units = append(fn.Units, ProfileBlock{
+				StartLine: int(r.uleb()),
+				StartCol:  int(r.uleb()),
+				EndLine:   int(r.uleb()),
+				EndCol:    int(r.uleb()),
+				NumStmt:   int(r.uleb()),
+			})
+		}
+		funcs[i] = fn
+	}
+	return funcs, r.err
+}
+
+// readCovCounters decodes a covcounters file and adds its counters to those
+// of the meta-data file it refers to.
+func readCovCounters(file string, metas map[string]*covMetaFile, counters map[string][][][]uint32) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	r := &covReader{data: data, order: binary.LittleEndian}
+	if !bytes.Equal(r.bytes(4), covCounterMagic) {
+		return errors.New("not a coverage counter data file")
+	}
+	if v := r.uint32(); v != 1 {
+		return fmt.Errorf("unsupported counter data file version %d", v)
+	}
+	hash := hex.EncodeToString(r.bytes(16))
+	flavor, bigEndian := r.byte(), r.byte() != 0
+	meta := metas[hash]
+	if meta == nil {
+		return fmt.Errorf("no %s%s file", covMetaPrefix, hash)
+	}
+	if len(data) < covCounterFooterSize || !bytes.Equal(data[len(data)-covCounterFooterSize:][:4], covCounterMagic) {
+		return errors.New("invalid counter data file footer")
+	}
+	r.seek(len(data) - covCounterFooterSize + 8)
+	segments := r.uint32()
+
+	read := r.uint32
+	switch {
+	case flavor == covCounterULEB128:
+		read = func() uint32 { return uint32(r.uleb()) }
+	case flavor != covCounterRaw:
+		return fmt.Errorf("unsupported counter flavor %d", flavor)
+	}
+	ctrs := counters[hash]
+	r.seek(covCounterFileHeaderSize)
+	for seg := uint32(0); seg < segments && r.err == nil; seg++ {
+		r.order = binary.LittleEndian
+		funcs := r.uint64()
+		strTabLen, argsLen := r.uint32(), r.uint32()
+		r.bytes(int(strTabLen) + int(argsLen))
+		r.seek((r.off + 3) &^ 3)
+		if bigEndian {
+			r.order = binary.BigEndian
+		}
+		for i := uint64(0); i < funcs && r.err == nil; i++ {
+			n, pkgIdx, funcIdx := read(), int(read()), int(read())
+			if r.err != nil {
+				break
+			}
+			if pkgIdx >= len(meta.Packages) || funcIdx >= len(meta.Packages[pkgIdx]) {
+				return fmt.Errorf("counters of unknown function %d of package %d", funcIdx, pkgIdx)
+			}
+			if uint64(n) > uint64(len(data)-r.off) {
+				// Every counter takes at least one byte.
+				return fmt.Errorf("counter count %d out of bounds", n)
+			}
+			for len(ctrs) <= pkgIdx {
+				ctrs = append(ctrs, nil)
+			}
+			for len(ctrs[pkgIdx]) <= funcIdx {
+				ctrs[pkgIdx] = append(ctrs[pkgIdx], nil)
+			}
+			fc := ctrs[pkgIdx][funcIdx]
+			for len(fc) < int(n) {
+				fc = append(fc, 0)
+			}
+			for c := 0; c < int(n); c++ {
+				if v := read(); meta.Mode == "set" {
+					fc[c] = uint32(setCount(int(fc[c] + v)))
+				} else {
+					fc[c] += v
+				}
+			}
+			ctrs[pkgIdx][funcIdx] = fc
+		}
+		r.bytes(covCounterFooterSize)
+	}
+	counters[hash] = ctrs
+	return r.err
+}
+
+// covReader decodes the primitives of the coverage data files. The first
+// error sticks and makes further reads return zero values.
+type covReader struct {
+	data  []byte
+	off   int
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *covReader) seek(off int) {
+	if r.err == nil && (off < 0 || off > len(r.data)) {
+		r.err = errors.New("offset out of bounds")
+	}
+	r.off = off
+}
+
+func (r *covReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data)-r.off {
+		r.err = errors.New("unexpected end of file")
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *covReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *covReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return r.order.Uint32(b)
+	}
+	return 0
+}
+
+func (r *covReader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return r.order.Uint64(b)
+	}
+	return 0
+}
+
+func (r *covReader) uleb() uint64 {
+	var v uint64
+	for shift := uint(0); r.err == nil; shift += 7 {
+		b := r.byte()
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 || shift > 63 {
+			break
+		}
+	}
+	return v
+}
+
+// stringTable reads a count followed by that many length-prefixed strings.
+func (r *covReader) stringTable() []string {
+	n := r.uleb()
+	var strs []string
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		strs = append(strs, string(r.bytes(int(r.uleb()))))
+	}
+	return strs
+}
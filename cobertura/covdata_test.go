@@ -0,0 +1,103 @@
+package cobertura
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadCoverDir(t *testing.T) {
+	f, err := os.Open("testdata/testdata_covdata.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := ParseProfiles(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadCoverDir("testdata/covdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, p := range got {
+			t.Logf("%s %s %+v", p.FileName, p.Mode, p.Blocks)
+		}
+		t.Errorf("Profiles differ from go tool covdata textfmt output")
+	}
+}
+
+func TestReadCoverDirErrors(t *testing.T) {
+	if _, err := ReadCoverDir("testdata"); err == nil {
+		t.Error("Expected an error for a directory without covmeta files")
+	}
+	if _, err := ReadCoverDir("testdata/missing"); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+// TestReadCoverDirCorrupt checks that damaged files, e.g. left by a crashed
+// program, are reported as errors instead of panicking.
+func TestReadCoverDirCorrupt(t *testing.T) {
+	const (
+		meta     = "covmeta.228d59545cd4bb58b3049ba1b6ff57eb"
+		counters = "covcounters.228d59545cd4bb58b3049ba1b6ff57eb.9303.1792260917300759139"
+	)
+	// The first function record of the counter file follows the file header,
+	// the segment header and its string and argument tables, 4-byte aligned.
+	record := func(data []byte) int {
+		off := covCounterFileHeaderSize + 16 + int(binary.LittleEndian.Uint32(data[40:])) + int(binary.LittleEndian.Uint32(data[44:]))
+		return (off + 3) &^ 3
+	}
+	tests := []struct {
+		name    string
+		file    string
+		corrupt func(data []byte) []byte
+	}{
+		{"truncated meta-data", meta, func(data []byte) []byte { return data[:60] }},
+		{"package count", meta, func(data []byte) []byte {
+			binary.LittleEndian.PutUint64(data[16:], 1<<62)
+			return data
+		}},
+		{"package offset", meta, func(data []byte) []byte {
+			binary.LittleEndian.PutUint64(data[covMetaFileHeaderSize:], ^uint64(0))
+			return data
+		}},
+		{"truncated counters", counters, func(data []byte) []byte { return data[:40] }},
+		{"package index", counters, func(data []byte) []byte {
+			data[record(data)+1] = 0x7f
+			return data
+		}},
+		{"counter count", counters, func(data []byte) []byte {
+			data[record(data)] = 0x7f
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "gocover-covdata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		for _, name := range []string{meta, counters} {
+			data, err := ioutil.ReadFile(filepath.Join("testdata/covdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name == tt.file {
+				data = tt.corrupt(data)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := ReadCoverDir(dir); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cobertura
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagnosticKind classifies the problems found while converting profiles.
+type DiagnosticKind int
+
+const (
+	// UnresolvedFile means a file named in the profile could not be found.
+	UnresolvedFile DiagnosticKind = iota
+	// UnparseableFile means a source file could not be read or parsed.
+	UnparseableFile
+	// MalformedLine means a profile line could not be parsed.
+	MalformedLine
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case UnresolvedFile:
+		return "unresolved file"
+	case UnparseableFile:
+		return "unparseable file"
+	case MalformedLine:
+		return "malformed profile line"
+	}
+	return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+}
+
+// Diagnostic describes a file or profile line that was left out of the report.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	File string // profile or source file name
+	Line int    // line number in File, or 0 if the whole file is affected
+	Err  error
+}
+
+func (d Diagnostic) Error() string {
+	pos := d.File
+	switch {
+	case pos == "" && d.Line > 0:
+		pos = fmt.Sprintf("line %d", d.Line)
+	case d.Line > 0:
+		pos = fmt.Sprintf("%s:%d", pos, d.Line)
+	}
+	if pos == "" {
+		return fmt.Sprintf("%s: %v", d.Kind, d.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", pos, d.Kind, d.Err)
+}
+
+// Diagnostics is a list of problems. As an error it is returned in strict
+// mode when the list is not empty.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.Error()
+	}
+	return fmt.Sprintf("%d problem(s) converting profiles:\n\t%s", len(ds), strings.Join(lines, "\n\t"))
+}
@@ -0,0 +1,72 @@
+package cobertura
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// Directives in source comments that exclude code from the report.
+const (
+	ignoreDirective      = "coverage:ignore"       // in the doc comment of a func
+	ignoreStartDirective = "coverage:ignore-start" // on the first line of a region
+	ignoreEndDirective   = "coverage:ignore-end"   // on the last line of a region
+)
+
+// directive returns the name of the directive in c, e.g. "coverage:ignore"
+// for "//coverage:ignore generated glue", or "" if c is not one. Like the
+// directives of the go command, it must have no space after the slashes.
+func directive(c *ast.Comment) string {
+	text := strings.TrimPrefix(c.Text, "//")
+	if text == c.Text || strings.HasPrefix(text, " ") {
+		return ""
+	}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// ignoredRegions returns the lines of f between ignore-start and ignore-end
+// directives, inclusive. A region without end runs to the end of the file.
+func (v *fileVisitor) ignoredRegions(f *ast.File) map[int]bool {
+	ignored := make(map[int]bool)
+	start := 0
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			line := v.fset.Position(c.Pos()).Line
+			switch directive(c) {
+			case ignoreStartDirective:
+				if start == 0 {
+					start = line
+				}
+			case ignoreEndDirective:
+				if start > 0 {
+					for l := start; l <= line; l++ {
+						ignored[l] = true
+					}
+					start = 0
+				}
+			}
+		}
+	}
+	if start > 0 {
+		end := v.fset.Position(f.End()).Line
+		for l := start; l <= end; l++ {
+			ignored[l] = true
+		}
+	}
+	return ignored
+}
+
+// ignoredFunc reports whether the doc comment of n has an ignore directive.
+func ignoredFunc(n *ast.FuncDecl) bool {
+	if n.Doc == nil {
+		return false
+	}
+	for _, c := range n.Doc.List {
+		if directive(c) == ignoreDirective {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package cobertura
+
+import "testing"
+
+func TestDirectives(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_directives.txt", Options{})
+	classes := cov.Packages[0].Classes
+	if len(classes) != 1 || len(classes[0].Methods) != 1 || classes[0].Methods[0].Name != "Region" {
+		t.Fatalf("Expected only the Region method; got %+v", classes)
+	}
+	var lines []int
+	for _, l := range classes[0].Methods[0].Lines {
+		lines = append(lines, l.Number)
+	}
+	if len(lines) != 3 || lines[0] != 9 || lines[1] != 10 || lines[2] != 17 {
+		t.Errorf("Expected lines 9, 10 and 17; got %v", lines)
+	}
+	if cov.LinesValid != 3 || cov.LinesCovered != 3 || cov.LinesExcluded != 3 {
+		t.Errorf("Got %d valid, %d covered and %d excluded lines; want 3, 3 and 3", cov.LinesValid, cov.LinesCovered, cov.LinesExcluded)
+	}
+}
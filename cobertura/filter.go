@@ -0,0 +1,156 @@
+package cobertura
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// regexpPrefix marks a filter pattern as a regular expression.
+const regexpPrefix = "re:"
+
+// generatedRe matches the comment marking generated files, see
+// https://golang.org/s/generatedcode.
+var generatedRe = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// pathFilter selects profile files by their file name or package path.
+type pathFilter struct {
+	include, exclude []func(string) bool
+}
+
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	f := &pathFilter{}
+	for _, p := range include {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, p := range exclude {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+// compilePattern compiles a path.Match pattern, or a regular expression if it
+// starts with "re:".
+func compilePattern(p string) (func(string) bool, error) {
+	if strings.HasPrefix(p, regexpPrefix) {
+		re, err := regexp.Compile(p[len(regexpPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", p, err)
+	}
+	return func(s string) bool {
+		ok, _ := path.Match(p, s)
+		return ok
+	}, nil
+}
+
+// match reports whether a file is converted: it or its package, named as in
+// Package.Name, must match an include pattern, if there are any, and neither
+// may match an exclude pattern.
+func (f *pathFilter) match(fileName string) bool {
+	fileName = filepath.ToSlash(fileName)
+	pkg := ""
+	if i := strings.LastIndex(fileName, "/"); i >= 0 {
+		pkg = fileName[:i]
+	}
+	matches := func(patterns []func(string) bool) bool {
+		for _, m := range patterns {
+			if m(fileName) || m(pkg) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(f.include) > 0 && !matches(f.include) {
+		return false
+	}
+	return !matches(f.exclude)
+}
+
+// isGenerated reports whether f has the comment marking generated files. As
+// for the go command, it must precede the package clause; comments further
+// down, or text in string literals, don't count.
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if generatedRe.MatchString(strings.TrimSuffix(c.Text, "\r")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// symbolPattern selects functions by qualifier and name, e.g. "*.String",
+// "(*Mock*).*" or "main.main".
+type symbolPattern struct {
+	pointer bool   // only methods with pointer receivers
+	qual    string // glob on the receiver type name, or the package name of functions
+	name    string // glob on the function name
+}
+
+func newSymbolPatterns(patterns []string) ([]symbolPattern, error) {
+	var sps []symbolPattern
+	for _, p := range patterns {
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid symbol pattern %q: want qualifier.name", p)
+		}
+		sp := symbolPattern{qual: p[:i], name: p[i+1:]}
+		if strings.HasPrefix(sp.qual, "(") && strings.HasSuffix(sp.qual, ")") {
+			sp.qual = sp.qual[1 : len(sp.qual)-1]
+			if strings.HasPrefix(sp.qual, "*") {
+				sp.pointer, sp.qual = true, sp.qual[1:]
+			}
+		}
+		for _, glob := range []string{sp.qual, sp.name} {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid symbol pattern %q: %v", p, err)
+			}
+		}
+		sps = append(sps, sp)
+	}
+	return sps, nil
+}
+
+// excludedSymbol reports whether the function with the given receiver, nil
+// for none, and name matches one of the symbol patterns. The qualifier of a
+// method is the name of its receiver type without type parameters, as the
+// brackets of a class name like List[...] can't be matched by a glob; that of
+// a function is the name of its package.
+func (v *fileVisitor) excludedSymbol(recv *ast.FieldList, name string) bool {
+	qual, pointer := v.pkgName, false
+	if recv != nil {
+		var typ ast.Expr
+		typ, pointer, _ = recvType(recv)
+		qual = types.ExprString(typ)
+	}
+	for _, sp := range v.symbols {
+		if sp.pointer && !pointer {
+			continue
+		}
+		if matchPattern(sp.qual, qual) && matchPattern(sp.name, name) {
+			return true
+		}
+	}
+	return false
+}
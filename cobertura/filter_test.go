@@ -0,0 +1,133 @@
+package cobertura
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestBuildFilters(t *testing.T) {
+	block := func(line, count int) []ProfileBlock {
+		return []ProfileBlock{{StartLine: line, StartCol: 1, EndLine: line + 1, EndCol: 1, NumStmt: 1, Count: count}}
+	}
+	profiles := []*Profile{
+		{FileName: "./testdata/func1.go", Mode: "set", Blocks: block(6, 1)},
+		{FileName: "./testdata/func2.go", Mode: "set", Blocks: block(9, 0)},
+		{FileName: "./testdata/generated.go", Mode: "set", Blocks: block(6, 0)},
+	}
+	tests := []struct {
+		opts  Options
+		files []string
+	}{
+		{Options{}, []string{"./testdata/func1.go", "./testdata/func2.go", "./testdata/generated.go"}},
+		{Options{SkipGenerated: true}, []string{"./testdata/func1.go", "./testdata/func2.go"}},
+		{Options{Exclude: []string{"*/*/func2.go"}}, []string{"./testdata/func1.go", "./testdata/generated.go"}},
+		{Options{Include: []string{`re:func\d\.go$`}, Exclude: []string{"re:2"}}, []string{"./testdata/func1.go"}},
+		{Options{Include: []string{"./testdata"}, SkipGenerated: true}, []string{"./testdata/func1.go", "./testdata/func2.go"}},
+		{Options{Exclude: []string{"./testdata"}}, nil},
+	}
+	for _, tt := range tests {
+		tt.opts.Strict = true
+		cov, err := Build(context.Background(), profiles, nil, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var files []string
+		for _, pkg := range cov.Packages {
+			for _, class := range pkg.Classes {
+				files = append(files, class.Filename)
+			}
+		}
+		if len(files) != len(tt.files) {
+			t.Errorf("%+v: got files %v; want %v", tt.opts, files, tt.files)
+			continue
+		}
+		for i := range files {
+			if files[i] != tt.files[i] {
+				t.Errorf("%+v: got files %v; want %v", tt.opts, files, tt.files)
+				break
+			}
+		}
+		if want := int64(len(tt.files)); cov.LinesValid != want {
+			t.Errorf("%+v: got %d valid lines; want %d", tt.opts, cov.LinesValid, want)
+		}
+	}
+}
+
+func TestBuildInvalidFilter(t *testing.T) {
+	for _, p := range []string{"[", "re:("} {
+		if _, err := Build(context.Background(), nil, nil, Options{Exclude: []string{p}}); err == nil {
+			t.Errorf("Expected error for pattern %q", p)
+		}
+	}
+}
+
+func TestBuildExcludeSymbols(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		methods  []string
+	}{
+		{nil, []string{"-.Func1", "Type1.Func2a", "Type1.Func2b", "Type1.Func2c"}},
+		{[]string{"*.Func2a"}, []string{"-.Func1", "Type1.Func2b", "Type1.Func2c"}},
+		{[]string{"(*Type1).*"}, []string{"-.Func1", "Type1.Func2a"}},
+		{[]string{"(*Type*).Func2[ab]", "testdata.*"}, []string{"Type1.Func2a", "Type1.Func2c"}},
+		{[]string{"other.Func1"}, []string{"-.Func1", "Type1.Func2a", "Type1.Func2b", "Type1.Func2c"}},
+	}
+	for _, tt := range tests {
+		cov := buildTestdata(t, "testdata/testdata_set.txt", Options{ExcludeSymbols: tt.patterns})
+		var methods []string
+		for _, class := range cov.Packages[0].Classes {
+			for _, m := range class.Methods {
+				methods = append(methods, class.Name+"."+m.Name)
+			}
+		}
+		if strings.Join(methods, " ") != strings.Join(tt.methods, " ") {
+			t.Errorf("%q: got methods %v; want %v", tt.patterns, methods, tt.methods)
+		}
+	}
+	for _, p := range []string{"String", "[.String"} {
+		if _, err := Build(context.Background(), nil, nil, Options{ExcludeSymbols: []string{p}}); err == nil {
+			t.Errorf("Expected error for symbol pattern %q", p)
+		}
+	}
+}
+
+func TestBuildExcludeSymbolsGeneric(t *testing.T) {
+	for _, style := range []TypeParamStyle{RuntimeTypeParams, BareTypeParams} {
+		opts := Options{TypeParams: style, ExcludeSymbols: []string{"List.Len", "(*Pair).*", "(*P*).Name"}}
+		cov := buildTestdata(t, "testdata/testdata_generics.txt", opts)
+		var methods []string
+		for _, class := range cov.Packages[0].Classes {
+			for _, m := range class.Methods {
+				methods = append(methods, m.Name)
+			}
+		}
+		if got := strings.Join(methods, " "); got != "Push Reset Key" {
+			t.Errorf("%v: got methods %s; want Push Reset Key", style, got)
+		}
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by stringer. DO NOT EDIT.\n\npackage p\n", true},
+		{"// Code generated by stringer. DO NOT EDIT.\r\n\r\npackage p\r\n", true},
+		{"// Copyright\n\n// Code generated by hand. DO NOT EDIT.\n\n// Package p.\npackage p\n", true},
+		{"package p\n\nconst header = `\n// Code generated by tmpl. DO NOT EDIT.\n`\n", false},
+		{"package p\n\n// Code generated by hand. DO NOT EDIT.\nfunc F() {}\n", false},
+	}
+	for _, tt := range tests {
+		f, err := parser.ParseFile(token.NewFileSet(), "p.go", tt.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isGenerated(f); got != tt.want {
+			t.Errorf("isGenerated(%q) = %v; want %v", tt.src, got, tt.want)
+		}
+	}
+}
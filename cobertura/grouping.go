@@ -0,0 +1,188 @@
+package cobertura
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ClassGrouping selects which functions share a class.
+type ClassGrouping int
+
+const (
+	// ReceiverClasses makes a class per receiver type and file, with the
+	// functions of the file in the class "-".
+	ReceiverClasses ClassGrouping = iota
+	// FileClasses makes a class per file, named by its base name, for tools
+	// that expect one class per file.
+	FileClasses
+	// TypeClasses makes a class per receiver type and file like
+	// ReceiverClasses, but lists the classes of a type in the files of a
+	// package together, for tools that merge classes by name. A class has a
+	// single file name, so a type can't share one across files.
+	TypeClasses
+)
+
+// ParseClassGrouping parses "receiver", "file" or "type".
+func ParseClassGrouping(s string) (ClassGrouping, error) {
+	switch s {
+	case "receiver":
+		return ReceiverClasses, nil
+	case "file":
+		return FileClasses, nil
+	case "type":
+		return TypeClasses, nil
+	}
+	return 0, fmt.Errorf("unknown class grouping %q: want receiver, file or type", s)
+}
+
+func (g ClassGrouping) String() string {
+	switch g {
+	case ReceiverClasses:
+		return "receiver"
+	case FileClasses:
+		return "file"
+	case TypeClasses:
+		return "type"
+	}
+	return fmt.Sprintf("ClassGrouping(%d)", int(g))
+}
+
+// FuncClassStyle selects the class of functions without receiver, and of
+// the initializers of package-level variables.
+type FuncClassStyle int
+
+const (
+	// DashFuncClass puts them in the class "-".
+	DashFuncClass FuncClassStyle = iota
+	// FileFuncClass puts them in a class named by the base name of the file.
+	FileFuncClass
+	// ConstructorFuncClass puts constructors, like NewServer or
+	// newServerWithOpts returning a *Server, in the class of the type they
+	// return, and other functions in a class named by the base name of the
+	// file.
+	ConstructorFuncClass
+)
+
+// ParseFuncClassStyle parses "dash", "file" or "constructor".
+func ParseFuncClassStyle(s string) (FuncClassStyle, error) {
+	switch s {
+	case "dash":
+		return DashFuncClass, nil
+	case "file":
+		return FileFuncClass, nil
+	case "constructor":
+		return ConstructorFuncClass, nil
+	}
+	return 0, fmt.Errorf("unknown function class style %q: want dash, file or constructor", s)
+}
+
+func (s FuncClassStyle) String() string {
+	switch s {
+	case DashFuncClass:
+		return "dash"
+	case FileFuncClass:
+		return "file"
+	case ConstructorFuncClass:
+		return "constructor"
+	}
+	return fmt.Sprintf("FuncClassStyle(%d)", int(s))
+}
+
+// classKey returns the name of the class for the class name from className,
+// and the key identifying it among the classes of the report. Keys include
+// the package, since files of different packages may be reported under the
+// same name, e.g. with Relative and several Sources.
+func (v *fileVisitor) classKey(className string) (name, key string) {
+	if v.opts.Grouping == FileClasses {
+		return path.Base(v.fileName), v.pkg.Name + "\x00" + v.fileName
+	}
+	return className, v.pkg.Name + "\x00" + v.fileName + "\x00" + className
+}
+
+// addClass adds class to the package: with TypeClasses and typed, after the
+// classes of the same type in other files, otherwise last.
+func (v *fileVisitor) addClass(class *Class, typed bool) {
+	classes := v.pkg.Classes
+	i := len(classes)
+	if v.opts.Grouping == TypeClasses && typed {
+		for j := len(classes) - 1; j >= 0; j-- {
+			if classes[j].Name == class.Name {
+				i = j + 1
+				break
+			}
+		}
+	}
+	classes = append(classes, nil)
+	copy(classes[i+1:], classes[i:])
+	classes[i] = class
+	v.pkg.Classes = classes
+}
+
+// className returns the class name for n, or for the initializers of
+// package-level variables if n is nil: that of its receiver, or for functions
+// without one as selected by the FuncClass option. typed reports whether it
+// names a type rather than the functions of a file.
+func (v *fileVisitor) className(n *ast.FuncDecl) (name string, typed bool) {
+	if n != nil && n.Recv != nil {
+		return v.recvName(n.Recv), true
+	}
+	switch v.opts.FuncClass {
+	case ConstructorFuncClass:
+		if n != nil {
+			if name, ok := v.constructorType(n); ok {
+				return name, true
+			}
+		}
+		return path.Base(v.fileName), false
+	case FileFuncClass:
+		return path.Base(v.fileName), false
+	}
+	return "-", false
+}
+
+// constructorType returns the class name of the type n constructs, if n looks
+// like a constructor: its name is New or new, optionally followed by an upper
+// case word, and its first result is a named type of the package, or a pointer
+// to one.
+func (v *fileVisitor) constructorType(n *ast.FuncDecl) (string, bool) {
+	rest := strings.TrimPrefix(n.Name.Name, "New")
+	if rest == n.Name.Name {
+		rest = strings.TrimPrefix(n.Name.Name, "new")
+	}
+	if rest == n.Name.Name {
+		return "", false
+	}
+	if r, _ := utf8.DecodeRuneInString(rest); rest != "" && !unicode.IsUpper(r) {
+		return "", false
+	}
+	results := n.Type.Results
+	if results == nil || len(results.List) == 0 {
+		return "", false
+	}
+	typ, _, generic := namedType(results.List[0].Type)
+	ident, ok := typ.(*ast.Ident)
+	if !ok || types.Universe.Lookup(ident.Name) != nil || isTypeParam(n, ident.Name) {
+		return "", false
+	}
+	return v.typeName(ident, generic), true
+}
+
+// isTypeParam reports whether name is a type parameter of n.
+func isTypeParam(n *ast.FuncDecl, name string) bool {
+	if n.Type.TypeParams == nil {
+		return false
+	}
+	for _, field := range n.Type.TypeParams.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
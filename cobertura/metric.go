@@ -0,0 +1,54 @@
+package cobertura
+
+import "fmt"
+
+// RateMetric selects what the line rates of the report measure.
+type RateMetric int
+
+const (
+	// LineMetric computes line rates from the reported lines, as Cobertura
+	// does.
+	LineMetric RateMetric = iota
+	// StatementMetric computes them from the statements of the profile
+	// blocks, so they match the coverage printed by go test -cover and
+	// go tool cover -func.
+	StatementMetric
+)
+
+// statementComment marks reports whose line rates measure statements.
+const statementComment = " line-rate attributes are statement coverage, as printed by go test -cover; lines-covered and lines-valid count lines "
+
+// ParseRateMetric parses "line" or "statement".
+func ParseRateMetric(s string) (RateMetric, error) {
+	switch s {
+	case "line":
+		return LineMetric, nil
+	case "statement":
+		return StatementMetric, nil
+	}
+	return 0, fmt.Errorf("unknown rate metric %q: want line or statement", s)
+}
+
+func (m RateMetric) String() string {
+	switch m {
+	case LineMetric:
+		return "line"
+	case StatementMetric:
+		return "statement"
+	}
+	return fmt.Sprintf("RateMetric(%d)", int(m))
+}
+
+// rated is implemented by the elements of the report that have a line rate.
+type rated interface {
+	HitRate() float32
+	StatementRate() float32
+}
+
+// rate returns the line rate of r in metric m.
+func (m RateMetric) rate(r rated) float32 {
+	if m == StatementMetric {
+		return r.StatementRate()
+	}
+	return r.HitRate()
+}
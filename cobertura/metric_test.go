@@ -0,0 +1,43 @@
+package cobertura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStatementMetric(t *testing.T) {
+	// Line 6 holds two blocks, so 7 of 8 statements but 6 of 7 lines ran.
+	tests := []struct {
+		metric   RateMetric
+		rate     float32
+		violated bool
+	}{
+		{LineMetric, 6.0 / 7, true},
+		{StatementMetric, 7.0 / 8, false},
+	}
+	for _, tt := range tests {
+		cov := buildTestdata(t, "testdata/testdata_funclits.txt", Options{Metric: tt.metric})
+		rates := []float32{cov.LineRate, cov.Packages[0].LineRate, cov.Packages[0].Classes[0].LineRate, cov.Packages[0].Classes[0].Methods[0].LineRate}
+		for i, rate := range rates {
+			if rate != tt.rate {
+				t.Errorf("%v: got line rate %v at level %d; want %v", tt.metric, rate, i, tt.rate)
+			}
+		}
+		if cov.LinesValid != 7 || cov.LinesCovered != 6 {
+			t.Errorf("%v: got %d/%d lines; want 6/7", tt.metric, cov.LinesCovered, cov.LinesValid)
+		}
+		err := cov.CheckThresholds([]Threshold{{Scope: TotalScope, Min: 0.86}})
+		if violated := err != nil; violated != tt.violated {
+			t.Errorf("%v: got threshold error %v", tt.metric, err)
+		}
+
+		var buf bytes.Buffer
+		if err := cov.WriteXML(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if commented := strings.Contains(buf.String(), "<!--"+statementComment+"-->"); commented != (tt.metric == StatementMetric) {
+			t.Errorf("%v: got comment %v in\n%s", tt.metric, commented, buf.String())
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package cobertura
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// module is a Go module whose sources are available in a local directory.
+type module struct {
+	Path string // module path, e.g. "example.com/mod"
+	Dir  string // directory holding the module's files
+}
+
+// moduleVersion identifies a module in the module cache.
+type moduleVersion struct {
+	Path    string
+	Version string
+}
+
+// moduleSet resolves import paths to directories the way the go command does
+// in module mode: local modules first, then the vendor directory, then the
+// module cache.
+type moduleSet struct {
+	Root     string                   // directory of the main module or workspace
+	Modules  []*module                // main modules followed by local replacements
+	Main     int                      // number of main modules in Modules
+	byPath   []*module                // Modules, longest path first
+	Requires map[string]moduleVersion // required modules with replacements applied
+	Vendor   bool                     // resolve dependencies from Root/vendor
+	ModCache string                   // GOMODCACHE
+}
+
+var (
+	modulesMu    sync.Mutex
+	modulesByDir = make(map[string]*moduleSet)
+)
+
+// modulesFor returns the modules of dir, or nil if it is not inside a module.
+// Results are cached, so concurrent conversions share them.
+func modulesFor(dir string) (*moduleSet, error) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if mods, ok := modulesByDir[dir]; ok {
+		return mods, nil
+	}
+	mods, err := loadModules(dir)
+	if err != nil {
+		return nil, err
+	}
+	modulesByDir[dir] = mods
+	return mods, nil
+}
+
+// loadModules reads the go.work or go.mod file governing dir. It returns nil
+// if there is none.
+func loadModules(dir string) (*moduleSet, error) {
+	work, err := findGoWork(dir)
+	if err != nil {
+		return nil, err
+	}
+	if work != "" {
+		return loadWorkspace(work)
+	}
+	root := findGoMod(dir)
+	if root == "" {
+		return nil, nil
+	}
+	gomod, err := parseGoMod(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	mods := &moduleSet{
+		Root:     root,
+		Modules:  []*module{{Path: gomod.Module, Dir: root}},
+		Main:     1,
+		Requires: make(map[string]moduleVersion),
+		Vendor:   vendorEnabled(root),
+		ModCache: modCacheDir(),
+	}
+	for p, v := range gomod.Require {
+		mods.Requires[p] = moduleVersion{Path: p, Version: v}
+	}
+	mods.addReplaces(root, gomod.Replace)
+	mods.sortModules()
+	return mods, nil
+}
+
+// loadWorkspace reads a go.work file and the go.mod files of the modules it
+// uses. Replacements in go.work take precedence over those of the modules.
+func loadWorkspace(file string) (*moduleSet, error) {
+	root := filepath.Dir(file)
+	work, err := parseGoWork(file)
+	if err != nil {
+		return nil, err
+	}
+	mods := &moduleSet{
+		Root:     root,
+		Main:     len(work.Use),
+		Requires: make(map[string]moduleVersion),
+		Vendor:   vendorEnabled(root),
+		ModCache: modCacheDir(),
+	}
+	dirs := make([]string, len(work.Use))
+	gomods := make([]*goModFile, len(work.Use))
+	for i, use := range work.Use {
+		dir := use
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, filepath.FromSlash(dir))
+		}
+		gomod, err := parseGoMod(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		dirs[i], gomods[i] = dir, gomod
+		mods.Modules = append(mods.Modules, &module{Path: gomod.Module, Dir: dir})
+		for p, v := range gomod.Require {
+			mods.Requires[p] = moduleVersion{Path: p, Version: v}
+		}
+	}
+	for i, gomod := range gomods {
+		mods.addReplaces(dirs[i], gomod.Replace)
+	}
+	mods.addReplaces(root, work.Replace)
+	for _, m := range mods.Modules {
+		delete(mods.Requires, m.Path)
+	}
+	mods.sortModules()
+	return mods, nil
+}
+
+// addReplaces applies replace directives found in the go.mod or go.work file
+// in dir, overriding earlier replacements of the same path.
+func (mods *moduleSet) addReplaces(dir string, replaces []replaceDirective) {
+	for _, r := range replaces {
+		mods.dropReplace(r.Old.Path)
+		if r.New.Version == "" && isLocalPath(r.New.Path) {
+			newDir := r.New.Path
+			if !filepath.IsAbs(newDir) {
+				newDir = filepath.Join(dir, filepath.FromSlash(newDir))
+			}
+			mods.Modules = append(mods.Modules, &module{Path: r.Old.Path, Dir: newDir})
+			delete(mods.Requires, r.Old.Path)
+			continue
+		}
+		mods.Requires[r.Old.Path] = r.New
+	}
+}
+
+// dropReplace removes the local replacement of the module path p, if any.
+func (mods *moduleSet) dropReplace(p string) {
+	kept := mods.Modules[:mods.Main]
+	for _, m := range mods.Modules[mods.Main:] {
+		if m.Path != p {
+			kept = append(kept, m)
+		}
+	}
+	mods.Modules = kept
+}
+
+// sortModules orders the modules for findFile, longest path first, so nested
+// modules win over the modules containing them.
+func (mods *moduleSet) sortModules() {
+	mods.byPath = make([]*module, len(mods.Modules))
+	copy(mods.byPath, mods.Modules)
+	sort.SliceStable(mods.byPath, func(i, j int) bool {
+		return len(mods.byPath[i].Path) > len(mods.byPath[j].Path)
+	})
+}
+
+// sourceDirs returns the root directory of every local module, main modules
+// first.
+func (mods *moduleSet) sourceDirs() []string {
+	dirs := make([]string, 0, len(mods.Modules))
+	seen := make(map[string]bool)
+	for _, m := range mods.Modules {
+		if !seen[m.Dir] {
+			seen[m.Dir] = true
+			dirs = append(dirs, m.Dir)
+		}
+	}
+	return dirs
+}
+
+// findFile returns the location of a file named by its import path, such as
+// "example.com/mod/pkg/file.go".
+func (mods *moduleSet) findFile(file string) (string, bool) {
+	for _, m := range mods.byPath {
+		if rel, ok := trimModulePath(file, m.Path); ok {
+			if p := filepath.Join(m.Dir, filepath.FromSlash(rel)); exists(p) {
+				return p, true
+			}
+		}
+	}
+	if mods.Vendor {
+		if p := filepath.Join(mods.Root, "vendor", filepath.FromSlash(file)); exists(p) {
+			return p, true
+		}
+	}
+	best := ""
+	for p := range mods.Requires {
+		if _, ok := trimModulePath(file, p); ok && len(p) > len(best) {
+			best = p
+		}
+	}
+	if best == "" || mods.ModCache == "" {
+		return "", false
+	}
+	rel, _ := trimModulePath(file, best)
+	mv := mods.Requires[best]
+	if isLocalPath(mv.Path) {
+		return "", false
+	}
+	p := filepath.Join(mods.ModCache, filepath.FromSlash(escapeModulePath(mv.Path)+"@"+escapeModulePath(mv.Version)), filepath.FromSlash(rel))
+	if !exists(p) {
+		return "", false
+	}
+	return p, true
+}
+
+// trimModulePath returns the part of file below the module path modPath.
+func trimModulePath(file, modPath string) (string, bool) {
+	if modPath == "" || !strings.HasPrefix(file, modPath+"/") {
+		return "", false
+	}
+	return file[len(modPath)+1:], true
+}
+
+// findGoMod returns the directory of the go.mod file governing dir.
+func findGoMod(dir string) string {
+	for {
+		if exists(filepath.Join(dir, "go.mod")) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// findGoWork returns the go.work file governing dir, honoring GOWORK, which
+// must be an absolute path like for the go command.
+func findGoWork(dir string) (string, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return "", nil
+	case "":
+	default:
+		if !filepath.IsAbs(gowork) {
+			return "", fmt.Errorf("invalid GOWORK: go.work file path must be absolute: %q", gowork)
+		}
+		return gowork, nil
+	}
+	for {
+		if p := filepath.Join(dir, "go.work"); exists(p) {
+			return p, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// vendorEnabled reports whether the go command would build the module in root
+// from its vendor directory.
+func vendorEnabled(root string) bool {
+	for _, f := range strings.Fields(os.Getenv("GOFLAGS")) {
+		switch f {
+		case "-mod=vendor":
+			return true
+		case "-mod=mod", "-mod=readonly":
+			return false
+		}
+	}
+	return exists(filepath.Join(root, "vendor", "modules.txt"))
+}
+
+// modCacheDir returns GOMODCACHE, defaulting to the first GOPATH entry.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	list := filepath.SplitList(build.Default.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		return ""
+	}
+	return filepath.Join(list[0], "pkg", "mod")
+}
+
+// escapeModulePath escapes upper-case letters the way the module cache does,
+// so "github.com/Azure" becomes "github.com/!azure".
+func escapeModulePath(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			buf.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func isLocalPath(p string) bool {
+	return p == "." || p == ".." ||
+		strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") ||
+		strings.HasPrefix(p, `.\`) || strings.HasPrefix(p, `..\`) ||
+		filepath.IsAbs(p) || path.IsAbs(p)
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// goModFile holds the parts of a go.mod file needed to locate sources.
+type goModFile struct {
+	Module  string
+	Require map[string]string
+	Replace []replaceDirective
+}
+
+type replaceDirective struct {
+	Old moduleVersion
+	New moduleVersion
+}
+
+// parseGoMod parses the module, require and replace directives of a go.mod
+// file. Other directives are ignored.
+func parseGoMod(file string) (*goModFile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	gomod := &goModFile{Require: make(map[string]string)}
+	err = parseDirectives(file, data, func(verb string, args []string) error {
+		switch verb {
+		case "module":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: module module/path")
+			}
+			gomod.Module = args[0]
+		case "require":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: require module/path v1.2.3")
+			}
+			gomod.Require[args[0]] = args[1]
+		case "replace":
+			r, err := parseReplace(args)
+			if err != nil {
+				return err
+			}
+			gomod.Replace = append(gomod.Replace, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if gomod.Module == "" {
+		return nil, fmt.Errorf("%s: no module declaration", file)
+	}
+	return gomod, nil
+}
+
+// goWorkFile holds the parts of a go.work file needed to locate sources.
+type goWorkFile struct {
+	Use     []string
+	Replace []replaceDirective
+}
+
+// parseGoWork parses the use and replace directives of a go.work file.
+func parseGoWork(file string) (*goWorkFile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	work := &goWorkFile{}
+	err = parseDirectives(file, data, func(verb string, args []string) error {
+		switch verb {
+		case "use":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: use local/dir")
+			}
+			work.Use = append(work.Use, args[0])
+		case "replace":
+			r, err := parseReplace(args)
+			if err != nil {
+				return err
+			}
+			work.Replace = append(work.Replace, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return work, nil
+}
+
+// parseReplace parses the arguments of a replace directive:
+// "old [v] => new [v]".
+func parseReplace(args []string) (replaceDirective, error) {
+	var r replaceDirective
+	arrow := -1
+	for i, a := range args {
+		if a == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || len(args)-arrow-1 < 1 || len(args)-arrow-1 > 2 {
+		return r, fmt.Errorf("usage: replace module/path [v1.2.3] => other/module v1.4 or local/directory")
+	}
+	r.Old.Path = args[0]
+	if arrow == 2 {
+		r.Old.Version = args[1]
+	}
+	r.New.Path = args[arrow+1]
+	if len(args) == arrow+3 {
+		r.New.Version = args[arrow+2]
+	}
+	return r, nil
+}
+
+// parseDirectives calls fn for every directive in a go.mod style file,
+// expanding "verb ( ... )" blocks into one call per line.
+func parseDirectives(file string, data []byte, fn func(verb string, args []string) error) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	block := ""
+	for lineno := 1; s.Scan(); lineno++ {
+		fields, err := splitDirective(s.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", file, lineno, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if err := fn(block, fields); err != nil {
+				return fmt.Errorf("%s:%d: %v", file, lineno, err)
+			}
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if err := fn(fields[0], fields[1:]); err != nil {
+			return fmt.Errorf("%s:%d: %v", file, lineno, err)
+		}
+	}
+	return s.Err()
+}
+
+// splitDirective splits a go.mod line into fields, dropping comments and
+// unquoting quoted strings.
+func splitDirective(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || strings.HasPrefix(line, "//") {
+			return fields, nil
+		}
+		if line[0] == '"' || line[0] == '`' {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, err
+			}
+			f, _ := strconv.Unquote(q)
+			fields = append(fields, f)
+			line = line[len(q):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		if i := strings.Index(line[:end], "//"); i > 0 {
+			end = i
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
@@ -0,0 +1,168 @@
+package cobertura
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindFileInModule(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocover-modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeFiles(t, root, map[string]string{
+		"mod/go.mod": `module example.com/mod // main module
+
+require (
+	example.com/other v0.0.0
+	example.com/Upper v1.2.3
+	example.com/vendored v1.0.0
+)
+
+replace example.com/other => ../other
+`,
+		"mod/pkg/x.go":                                "package pkg\n",
+		"other/go.mod":                                "module example.com/other\n",
+		"other/y.go":                                  "package other\n",
+		"cache/example.com/!upper@v1.2.3/z/z.go":      "package z\n",
+		"mod/vendor/modules.txt":                      "# example.com/vendored v1.0.0\n",
+		"mod/vendor/example.com/vendored/v.go":        "package vendored\n",
+		"cache/example.com/vendored@v1.0.0/v.go":      "package vendored\n",
+		"cache/example.com/vendored@v1.0.0/cached.go": "package vendored\n",
+	})
+
+	defer os.Setenv("GOMODCACHE", os.Getenv("GOMODCACHE"))
+	os.Setenv("GOMODCACHE", filepath.Join(root, "cache"))
+	defer os.Setenv("GOFLAGS", os.Getenv("GOFLAGS"))
+	os.Setenv("GOFLAGS", "")
+	dir := filepath.Join(root, "mod", "pkg")
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"example.com/mod/pkg/x.go", "mod/pkg/x.go"},
+		{"example.com/other/y.go", "other/y.go"},
+		{"example.com/Upper/z/z.go", "cache/example.com/!upper@v1.2.3/z/z.go"},
+		{"example.com/vendored/v.go", "mod/vendor/example.com/vendored/v.go"},
+		{"example.com/vendored/cached.go", "cache/example.com/vendored@v1.0.0/cached.go"},
+	}
+	for _, tt := range tests {
+		got, err := findFile(dir, tt.file)
+		if err != nil {
+			t.Errorf("findFile(%q): %v", tt.file, err)
+			continue
+		}
+		want := filepath.Join(root, filepath.FromSlash(tt.want))
+		gotInfo, err1 := os.Stat(got)
+		wantInfo, err2 := os.Stat(want)
+		if err1 != nil || err2 != nil || !os.SameFile(gotInfo, wantInfo) {
+			t.Errorf("findFile(%q) = %q; want %q", tt.file, got, want)
+		}
+	}
+}
+
+func TestParseGoModErrors(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocover-modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeFiles(t, root, map[string]string{
+		"nomodule/go.mod":   "go 1.21\n",
+		"badreplace/go.mod": "module example.com/m\nreplace example.com/x =>\n",
+	})
+	if _, err := parseGoMod(filepath.Join(root, "nomodule", "go.mod")); err == nil {
+		t.Error("expected error for go.mod without module declaration")
+	}
+	if _, err := parseGoMod(filepath.Join(root, "badreplace", "go.mod")); err == nil {
+		t.Error("expected error for malformed replace directive")
+	}
+}
+
+func TestFindFileInWorkspace(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocover-workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeFiles(t, root, map[string]string{
+		"go.work": `go 1.21
+
+use (
+	./a
+	./b
+)
+
+replace example.com/ext => ./ext
+`,
+		// go.work overrides the replacement of the module.
+		"a/go.mod":      "module example.com/a\n\nrequire example.com/ext v1.0.0\n\nreplace example.com/ext => ../oldext\n",
+		"a/a.go":        "package a\n",
+		"b/go.mod":      "module example.com/a/b\n",
+		"b/b.go":        "package b\n",
+		"ext/go.mod":    "module example.com/ext\n",
+		"ext/ext.go":    "package ext\n",
+		"oldext/go.mod": "module example.com/ext\n",
+		"oldext/ext.go": "package ext\n",
+		"a/sub/sub.go":  "package sub\n",
+	})
+
+	defer os.Setenv("GOWORK", os.Getenv("GOWORK"))
+	os.Setenv("GOWORK", "")
+	mods, err := loadModules(filepath.Join(root, "a", "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mods == nil {
+		t.Fatal("go.work not found")
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"example.com/a/a.go", "a/a.go"},
+		{"example.com/a/sub/sub.go", "a/sub/sub.go"},
+		{"example.com/a/b/b.go", "b/b.go"},
+		{"example.com/ext/ext.go", "ext/ext.go"},
+	}
+	for _, tt := range tests {
+		got, ok := mods.findFile(tt.file)
+		if want := filepath.Join(root, filepath.FromSlash(tt.want)); !ok || got != want {
+			t.Errorf("findFile(%q) = %q, %v; want %q", tt.file, got, ok, want)
+		}
+	}
+
+	dirs := mods.sourceDirs()
+	want := []string{filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "ext")}
+	if len(dirs) != len(want) {
+		t.Fatalf("sourceDirs() = %q; want %q", dirs, want)
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Errorf("sourceDirs()[%d] = %q; want %q", i, dirs[i], want[i])
+		}
+	}
+
+	// The go command rejects a relative GOWORK.
+	os.Setenv("GOWORK", "../go.work")
+	if _, err := loadModules(filepath.Join(root, "a")); err == nil {
+		t.Error("expected error for relative GOWORK")
+	}
+}
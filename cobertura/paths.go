@@ -0,0 +1,69 @@
+package cobertura
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Rewrite replaces the leading From path of profile file names with To, e.g.
+// to map the paths of a container the tests ran in onto the checkout.
+type Rewrite struct {
+	From, To string
+}
+
+// ParseRewrite parses a rewrite rule given as "from=to".
+func ParseRewrite(s string) (Rewrite, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return Rewrite{}, fmt.Errorf("invalid rewrite rule %q: want from=to", s)
+	}
+	return Rewrite{From: s[:i], To: s[i+1:]}, nil
+}
+
+// rewritePath applies the first rule whose From is a leading path of name.
+func rewritePath(rules []Rewrite, name string) string {
+	for _, r := range rules {
+		if !strings.HasPrefix(name, r.From) {
+			continue
+		}
+		rest := name[len(r.From):]
+		if rest == "" || strings.HasSuffix(r.From, "/") || rest[0] == '/' {
+			return r.To + rest
+		}
+	}
+	return name
+}
+
+// sourceRoots returns the absolute directories report file names are made
+// relative to: the Sources option, or else the root of the module or
+// workspace containing dir, or else dir itself.
+func sourceRoots(opts Options, dir string) []string {
+	if len(opts.Sources) == 0 {
+		if mods, err := modulesFor(dir); err == nil && mods != nil {
+			return []string{mods.Root}
+		}
+		return []string{dir}
+	}
+	roots := make([]string, len(opts.Sources))
+	for i, s := range opts.Sources {
+		if !filepath.IsAbs(s) {
+			s = filepath.Join(dir, s)
+		}
+		roots[i] = filepath.Clean(s)
+	}
+	return roots
+}
+
+// relativeName returns file, an absolute path, relative to the first of roots
+// containing it, with forward slashes.
+func relativeName(roots []string, file string) (string, bool) {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
+}
@@ -0,0 +1,135 @@
+package cobertura
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewritePath(t *testing.T) {
+	rules := []Rewrite{{"/builds/app", "."}, {"/src/", "github.com/org/"}}
+	tests := []struct{ in, want string }{
+		{"/builds/app/pkg/a.go", "./pkg/a.go"},
+		{"/builds/application/a.go", "/builds/application/a.go"},
+		{"/src/repo/a.go", "github.com/org/repo/a.go"},
+		{"github.com/org/repo/a.go", "github.com/org/repo/a.go"},
+	}
+	for _, tt := range tests {
+		if got := rewritePath(rules, tt.in); got != tt.want {
+			t.Errorf("rewritePath(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseRewrite("=x"); err == nil {
+		t.Error("Expected error for rule without from")
+	}
+	if r, err := ParseRewrite("/a=/b=c"); err != nil || r.From != "/a" || r.To != "/b=c" {
+		t.Errorf("ParseRewrite = %+v, %v", r, err)
+	}
+}
+
+func TestBuildRelative(t *testing.T) {
+	profiles := []*Profile{{
+		FileName: "/container/src/testdata/func1.go",
+		Mode:     "set",
+		Blocks:   []ProfileBlock{{StartLine: 4, StartCol: 23, EndLine: 5, EndCol: 16, NumStmt: 1, Count: 1}},
+	}}
+	opts := Options{
+		Strict:   true,
+		Relative: true,
+		Sources:  []string{"testdata"},
+		Rewrites: []Rewrite{{From: "/container/src", To: "."}},
+	}
+	cov, err := Build(context.Background(), profiles, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if len(cov.Sources) != 1 || cov.Sources[0].Path != filepath.Join(wd, "testdata") {
+		t.Errorf("Unexpected sources %+v", cov.Sources)
+	}
+	if class := cov.Packages[0].Classes[0]; class.Filename != "func1.go" {
+		t.Errorf("Got filename %q; want func1.go", class.Filename)
+	}
+	if name := cov.Packages[0].Name; name != "./testdata" {
+		t.Errorf("Got package %q; want ./testdata", name)
+	}
+}
+
+func TestBuildRelativeToModule(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocover-relative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeFiles(t, root, map[string]string{
+		"go.mod":       "module example.com/mod\n",
+		"pkg/x.go":     "package pkg\n\nfunc X() {\n}\n",
+		"cmd/c/sub.go": "package main\n",
+	})
+	profiles := []*Profile{{
+		FileName: "example.com/mod/pkg/x.go",
+		Mode:     "set",
+		Blocks:   []ProfileBlock{{StartLine: 3, StartCol: 10, EndLine: 4, EndCol: 2, Count: 1}},
+	}}
+	cov, err := Build(context.Background(), profiles, nil, Options{Strict: true, Relative: true, Dir: filepath.Join(root, "cmd", "c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cov.Sources) != 1 || cov.Sources[0].Path != root {
+		t.Errorf("Unexpected sources %+v", cov.Sources)
+	}
+	if class := cov.Packages[0].Classes[0]; class.Filename != "pkg/x.go" {
+		t.Errorf("Got filename %q; want pkg/x.go", class.Filename)
+	}
+}
+
+func TestBuildRelativeSameName(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocover-relative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeFiles(t, root, map[string]string{
+		"a/x.go": "package a\n\nfunc A() {\n\tprintln()\n}\n",
+		"b/x.go": "package b\n\nfunc B(x bool) {\n\tif x {\n\t\tprintln()\n\t}\n}\n",
+	})
+	profiles := []*Profile{{
+		FileName: "./a/x.go",
+		Mode:     "set",
+		Blocks:   []ProfileBlock{{StartLine: 3, StartCol: 10, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1}},
+	}, {
+		FileName: "./b/x.go",
+		Mode:     "set",
+		Blocks: []ProfileBlock{
+			{StartLine: 3, StartCol: 16, EndLine: 4, EndCol: 7, NumStmt: 1, Count: 1},
+			{StartLine: 4, StartCol: 7, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+		},
+	}}
+	for _, grouping := range []ClassGrouping{ReceiverClasses, FileClasses, TypeClasses} {
+		opts := Options{Strict: true, Relative: true, Sources: []string{"a", "b"}, Dir: root, Grouping: grouping}
+		cov, err := Build(context.Background(), profiles, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []struct {
+			pkg, method string
+			rate        float32
+		}{{"./a", "A", 1}, {"./b", "B", 0.5}}
+		if len(cov.Packages) != len(want) {
+			t.Fatalf("%v: got %d packages; want %d", grouping, len(cov.Packages), len(want))
+		}
+		for i, w := range want {
+			pkg := cov.Packages[i]
+			if pkg.Name != w.pkg || len(pkg.Classes) != 1 || pkg.LineRate != w.rate {
+				t.Errorf("%v: got package %s %v with %d classes; want %s %v with 1", grouping, pkg.Name, pkg.LineRate, len(pkg.Classes), w.pkg, w.rate)
+				continue
+			}
+			class := pkg.Classes[0]
+			if class.Filename != "x.go" || len(class.Methods) != 1 || class.Methods[0].Name != w.method || class.LineRate != w.rate {
+				t.Errorf("%v: got class %+v in %s; want x.go with %s", grouping, class, pkg.Name, w.method)
+			}
+		}
+	}
+}
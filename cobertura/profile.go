@@ -0,0 +1,375 @@
+// Imported from https://code.google.com/p/go/source/browse/cmd/cover/profile.go?repo=tools&r=c10a9dd5e0b0a859a8385b6f004584cb083a3934
+
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cobertura
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile represents the profiling data for a specific file.
+type Profile struct {
+	FileName string
+	Mode     string
+	Blocks   []ProfileBlock
+}
+
+// ProfileBlock represents a single block of profiling data.
+type ProfileBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	NumStmt, Count      int
+}
+
+// modePrefix starts the first line of a profile, which names its mode.
+const modePrefix = "mode: "
+
+type byFileName []*Profile
+
+func (p byFileName) Len() int           { return len(p) }
+func (p byFileName) Less(i, j int) bool { return p[i].FileName < p[j].FileName }
+func (p byFileName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// ParseProfiles parses profile data from the given Reader and returns a
+// Profile for each file. The data may be several profiles concatenated, each
+// starting with its own mode line; they are merged as by MergeProfiles.
+func ParseProfiles(in io.Reader) ([]*Profile, error) {
+	return parseProfiles(in, "", nil)
+}
+
+// ReadProfiles is like ParseProfiles, but skips malformed lines and returns
+// them as diagnostics. name identifies the input in the diagnostics.
+func ReadProfiles(in io.Reader, name string) ([]*Profile, Diagnostics, error) {
+	var diags Diagnostics
+	profiles, err := parseProfiles(in, name, &diags)
+	return profiles, diags, err
+}
+
+// WriteProfiles writes profiles in the format of go test -coverprofile, in
+// the mode of the first profile.
+func WriteProfiles(out io.Writer, profiles []*Profile) error {
+	mode := "set"
+	if len(profiles) > 0 {
+		mode = profiles[0].Mode
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "%s%s\n", modePrefix, mode)
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			fmt.Fprintf(w, "%s:%d.%d,%d.%d %d %d\n", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return w.Flush()
+}
+
+// parseProfiles is like ParseProfiles, but if diags is not nil malformed lines
+// are recorded there and skipped instead of failing the parse. name is the
+// profile file name used in diagnostics.
+func parseProfiles(in io.Reader, name string, diags *Diagnostics) ([]*Profile, error) {
+	var profiles []*Profile
+	files := make(map[string]*Profile)
+	// First line is "mode: foo", where foo is "set", "count", or "atomic".
+	// Rest of file is in the format
+	//      encoding/base64/base64.go:34.44,37.40 3 1
+	// where the fields are: name.go:line.column,line.column numberOfStatements count
+	// Another mode line starts the next of several concatenated profiles.
+	s := bufio.NewScanner(in)
+	mode := ""
+	for lineno := 1; s.Scan(); lineno++ {
+		line := s.Text()
+		if mode == "" || strings.HasPrefix(line, modePrefix) {
+			if !strings.HasPrefix(line, modePrefix) || line == modePrefix {
+				return nil, fmt.Errorf("bad mode line: %v", line)
+			}
+			if mode != "" {
+				profiles = MergeProfiles(profiles, sortedProfiles(files))
+				files = make(map[string]*Profile)
+			}
+			mode = line[len(modePrefix):]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fn, block, err := parseLine(line)
+		if err != nil {
+			d := Diagnostic{Kind: MalformedLine, File: name, Line: lineno, Err: err}
+			if diags == nil {
+				return nil, d
+			}
+			*diags = append(*diags, d)
+			continue
+		}
+		p := files[fn]
+		if p == nil {
+			p = &Profile{
+				FileName: fn,
+				Mode:     mode,
+			}
+			files[fn] = p
+		}
+		p.Blocks = append(p.Blocks, block)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if profiles == nil {
+		return sortedProfiles(files), nil
+	}
+	return MergeProfiles(profiles, sortedProfiles(files)), nil
+}
+
+// sortedProfiles returns the profiles in files sorted by file name, with
+// their blocks sorted by position and duplicates combined.
+func sortedProfiles(files map[string]*Profile) []*Profile {
+	for _, p := range files {
+		p.Blocks = combineDuplicates(p.Mode, p.Blocks)
+	}
+	// Generate a sorted slice.
+	profiles := make([]*Profile, 0, len(files))
+	for _, profile := range files {
+		profiles = append(profiles, profile)
+	}
+	sort.Sort(byFileName(profiles))
+	return profiles
+}
+
+// MergeProfiles merges the profiles in more, e.g. from another test run, into
+// those in profiles and returns the result sorted by file name. Blocks of the
+// same file at the same position are combined as by combineDuplicates. The
+// result has a single mode: when a profile in set mode is merged with one in
+// count or atomic mode, of any file, counts are reduced to 0 or 1 and the
+// result is in set mode; count and atomic mix freely and keep the mode of the
+// first profile.
+func MergeProfiles(profiles, more []*Profile) []*Profile {
+	mode := mergedMode(profiles, more)
+	files := make(map[string]*Profile, len(profiles))
+	for _, p := range profiles {
+		files[p.FileName] = p
+	}
+	for _, p := range more {
+		if dst := files[p.FileName]; dst != nil {
+			dst.merge(p)
+			continue
+		}
+		files[p.FileName] = p
+		profiles = append(profiles, p)
+	}
+	for _, p := range profiles {
+		if p.Mode != mode {
+			p.Mode = mode
+			p.Blocks = combineDuplicates(mode, p.Blocks)
+		}
+	}
+	sort.Sort(byFileName(profiles))
+	return profiles
+}
+
+// mergedMode returns the mode of the profiles merged by MergeProfiles: set if
+// any is in set mode, otherwise that of the first.
+func mergedMode(profiles, more []*Profile) string {
+	mode := ""
+	for _, ps := range [][]*Profile{profiles, more} {
+		for _, p := range ps {
+			if mode == "" || p.Mode == "set" {
+				mode = p.Mode
+			}
+		}
+	}
+	return mode
+}
+
+// merge adds the blocks of src to p, combining those at the same position.
+func (p *Profile) merge(src *Profile) {
+	if p.Mode != src.Mode && (p.Mode == "set" || src.Mode == "set") {
+		p.Mode = "set"
+	}
+	p.Blocks = combineDuplicates(p.Mode, append(p.Blocks, src.Blocks...))
+}
+
+// combineDuplicates returns the blocks sorted by position, with blocks that
+// were recorded more than once at the same position combined into one. This
+// happens when profiles are merged and with go test -coverpkg, where every
+// test binary reports the blocks of the shared packages. Counts are summed in
+// count and atomic mode and OR'ed in set mode, so a block counts as covered
+// if any test covered it.
+func combineDuplicates(mode string, blocks []ProfileBlock) []ProfileBlock {
+	sorted := make([]ProfileBlock, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		bi, bj := sorted[i], sorted[j]
+		if bi.StartLine != bj.StartLine || bi.StartCol != bj.StartCol {
+			return after(bj.StartLine, bj.StartCol, bi.StartLine, bi.StartCol)
+		}
+		return after(bj.EndLine, bj.EndCol, bi.EndLine, bi.EndCol)
+	})
+	out := sorted[:0]
+	for _, b := range sorted {
+		if mode == "set" {
+			b.Count = setCount(b.Count)
+		}
+		if n := len(out); n > 0 && out[n-1].samePos(b) {
+			if mode == "set" {
+				out[n-1].Count = setCount(out[n-1].Count + b.Count)
+			} else {
+				out[n-1].Count += b.Count
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (b ProfileBlock) samePos(o ProfileBlock) bool {
+	return b.StartLine == o.StartLine && b.StartCol == o.StartCol &&
+		b.EndLine == o.EndLine && b.EndCol == o.EndCol
+}
+
+func setCount(count int) int {
+	if count > 0 {
+		return 1
+	}
+	return 0
+}
+
+var lineRe = regexp.MustCompile(`^(.+):([0-9]+).([0-9]+),([0-9]+).([0-9]+) ([0-9]+) ([0-9]+)$`)
+
+// parseLine parses a profile line into its file name and block.
+func parseLine(line string) (string, ProfileBlock, error) {
+	m := lineRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", ProfileBlock{}, fmt.Errorf("%q doesn't match expected format: %v", line, lineRe)
+	}
+	var nums [6]int
+	for i := range nums {
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return "", ProfileBlock{}, fmt.Errorf("%q: %v", line, err)
+		}
+		nums[i] = n
+	}
+	return m[1], ProfileBlock{
+		StartLine: nums[0],
+		StartCol:  nums[1],
+		EndLine:   nums[2],
+		EndCol:    nums[3],
+		NumStmt:   nums[4],
+		Count:     nums[5],
+	}, nil
+}
+
+// Boundary represents the position in a source file of the beginning or end of a
+// block as reported by the coverage profile. In HTML mode, it will correspond to
+// the opening or closing of a <span> tag and will be used to colorize the source
+type Boundary struct {
+	Offset int     // Location as a byte offset in the source file.
+	Start  bool    // Is this the start of a block?
+	Count  int     // Event count from the cover profile.
+	Norm   float64 // Count normalized to [0..1].
+}
+
+// Boundaries returns a Profile as a set of Boundary objects within the provided src.
+func (p *Profile) Boundaries(src []byte) (boundaries []Boundary) {
+	// Find maximum count.
+	max := 0
+	for _, b := range p.Blocks {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	// Divisor for normalization.
+	divisor := math.Log(float64(max))
+
+	// boundary returns a Boundary, populating the Norm field with a normalized Count.
+	boundary := func(offset int, start bool, count int) Boundary {
+		b := Boundary{Offset: offset, Start: start, Count: count}
+		if !start || count == 0 {
+			return b
+		}
+		if max <= 1 {
+			b.Norm = 0.8 // Profile is in"set" mode; we want a heat map. Use cov8 in the CSS.
+		} else if count > 0 {
+			b.Norm = math.Log(float64(count)) / divisor
+		}
+		return b
+	}
+
+	line, col := 1, 2 // TODO: Why is this 2?
+	for si, bi := 0, 0; si < len(src) && bi < len(p.Blocks); {
+		b := p.Blocks[bi]
+		if b.StartLine == line && b.StartCol == col {
+			boundaries = append(boundaries, boundary(si, true, b.Count))
+		}
+		if b.EndLine == line && b.EndCol == col {
+			boundaries = append(boundaries, boundary(si, false, 0))
+			bi++
+			continue // Don't advance through src; maybe the next block starts here.
+		}
+		if src[si] == '\n' {
+			line++
+			col = 0
+		}
+		col++
+		si++
+	}
+	sort.Sort(boundariesByPos(boundaries))
+	return
+}
+
+type boundariesByPos []Boundary
+
+func (b boundariesByPos) Len() int      { return len(b) }
+func (b boundariesByPos) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b boundariesByPos) Less(i, j int) bool {
+	if b[i].Offset == b[j].Offset {
+		return !b[i].Start && b[j].Start
+	}
+	return b[i].Offset < b[j].Offset
+}
+
+// findFile finds the location of the named file relative to dir, in the module
+// containing dir, its replacements, vendor directory and the module cache, or
+// in GOROOT, GOPATH etc.
+func findFile(dir, file string) (string, error) {
+	if strings.HasPrefix(file, "_") {
+		file = file[1:]
+	}
+	if p := file; filepath.IsAbs(p) || dir == "" {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	} else if p := filepath.Join(dir, p); exists(p) {
+		return p, nil
+	}
+	mods, err := modulesFor(dir)
+	if err != nil {
+		return "", fmt.Errorf("can't find %q: %v", file, err)
+	}
+	if mods != nil {
+		if p, ok := mods.findFile(file); ok {
+			return p, nil
+		}
+	}
+	pkgDir, file := filepath.Split(file)
+	ctxt := build.Default
+	ctxt.Dir = dir
+	pkg, err := ctxt.Import(pkgDir, dir, build.FindOnly)
+	if err != nil {
+		return "", fmt.Errorf("can't find %q: %v", file, err)
+	}
+	return filepath.Join(pkg.Dir, file), nil
+}
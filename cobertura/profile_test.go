@@ -0,0 +1,108 @@
+package cobertura
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProfilesConcatenated(t *testing.T) {
+	data := `mode: count
+a.go:1.1,2.2 1 1
+a.go:3.1,4.2 1 0
+mode: count
+a.go:1.1,2.2 1 2
+b.go:1.1,2.2 1 0
+mode: atomic
+a.go:3.1,4.2 1 5
+`
+	profiles, err := ParseProfiles(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].FileName != "a.go" || profiles[1].FileName != "b.go" {
+		t.Fatalf("Unexpected profiles: %+v", profiles)
+	}
+	a := profiles[0]
+	if a.Mode != "count" || len(a.Blocks) != 2 || a.Blocks[0].Count != 3 || a.Blocks[1].Count != 5 {
+		t.Errorf("Expected summed counts 3 and 5 in count mode; got %s %+v", a.Mode, a.Blocks)
+	}
+}
+
+func TestMergeProfilesModes(t *testing.T) {
+	tests := []struct {
+		modes  [2]string
+		counts [2]int
+		mode   string
+		count  int
+	}{
+		{[2]string{"set", "set"}, [2]int{1, 1}, "set", 1},
+		{[2]string{"set", "set"}, [2]int{0, 1}, "set", 1},
+		{[2]string{"set", "set"}, [2]int{0, 0}, "set", 0},
+		{[2]string{"count", "atomic"}, [2]int{2, 3}, "count", 5},
+		{[2]string{"count", "set"}, [2]int{2, 0}, "set", 1},
+		{[2]string{"set", "atomic"}, [2]int{0, 7}, "set", 1},
+	}
+	for _, tt := range tests {
+		var in [2][]*Profile
+		for i := range in {
+			in[i] = []*Profile{{
+				FileName: "a.go",
+				Mode:     tt.modes[i],
+				Blocks:   []ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: tt.counts[i]}},
+			}}
+		}
+		merged := MergeProfiles(in[0], in[1])
+		if len(merged) != 1 || len(merged[0].Blocks) != 1 {
+			t.Fatalf("%v: unexpected merge result %+v", tt.modes, merged)
+		}
+		if p := merged[0]; p.Mode != tt.mode || p.Blocks[0].Count != tt.count {
+			t.Errorf("%v %v: got mode %s count %d; want %s %d", tt.modes, tt.counts, p.Mode, p.Blocks[0].Count, tt.mode, tt.count)
+		}
+	}
+}
+
+func TestMergeProfilesModesAcrossFiles(t *testing.T) {
+	set := []*Profile{{FileName: "a.go", Mode: "set", Blocks: []ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 1}}}}
+	count := []*Profile{{FileName: "b.go", Mode: "count", Blocks: []ProfileBlock{{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 2, NumStmt: 1, Count: 7}}}}
+	merged := MergeProfiles(count, set)
+	for _, p := range merged {
+		if p.Mode != "set" || p.Blocks[0].Count != 1 {
+			t.Errorf("%s: got mode %s count %d; want set 1", p.FileName, p.Mode, p.Blocks[0].Count)
+		}
+	}
+	var out strings.Builder
+	if err := WriteProfiles(&out, merged); err != nil {
+		t.Fatal(err)
+	}
+	if want := "mode: set\na.go:1.1,2.2 1 1\nb.go:1.1,2.2 1 1\n"; out.String() != want {
+		t.Errorf("Got profile\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestParseProfilesDuplicateBlocks(t *testing.T) {
+	// go test -coverpkg writes the blocks of shared packages once per test binary.
+	tests := []struct {
+		mode  string
+		hits  string
+		count int
+	}{
+		{"set", "1", 1},
+		{"count", "3", 3},
+		{"atomic", "3", 3},
+	}
+	for _, tt := range tests {
+		data := "mode: " + tt.mode + "\n" +
+			"a.go:1.1,2.2 1 0\n" +
+			"a.go:1.1,5.2 1 0\n" +
+			"a.go:1.1,2.2 1 " + tt.hits + "\n" +
+			"a.go:1.1,2.2 1 0\n"
+		profiles, err := ParseProfiles(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks := profiles[0].Blocks
+		if len(blocks) != 2 || blocks[0].EndLine != 2 || blocks[0].Count != tt.count || blocks[1].Count != 0 {
+			t.Errorf("%s: unexpected blocks %+v", tt.mode, blocks)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cobertura
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// sourceDateEpoch is the environment variable that fixes the time of
+// reproducible builds, see https://reproducible-builds.org/specs/source-date-epoch/.
+const sourceDateEpoch = "SOURCE_DATE_EPOCH"
+
+// rateDigits is the number of decimal digits rates and complexities are
+// rounded to in reproducible reports.
+const rateDigits = 4
+
+// reportTime returns the time of the report: opts.Timestamp, or in
+// reproducible mode SOURCE_DATE_EPOCH, or else the current time.
+func reportTime(opts Options) (time.Time, error) {
+	if !opts.Timestamp.IsZero() {
+		return opts.Timestamp, nil
+	}
+	if !opts.Reproducible {
+		return time.Now(), nil
+	}
+	s := os.Getenv(sourceDateEpoch)
+	if s == "" {
+		return time.Time{}, errors.New("reproducible output needs a timestamp or " + sourceDateEpoch)
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want Unix seconds", sourceDateEpoch, s)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// normalize sorts packages by name, classes by file and name, methods by name
+// and lines by number, and rounds rates and complexities to rateDigits, so the
+// report doesn't depend on the order of the profiles or on float formatting.
+func (cov *Coverage) normalize() {
+	sort.SliceStable(cov.Packages, func(i, j int) bool {
+		return cov.Packages[i].Name < cov.Packages[j].Name
+	})
+	cov.LineRate, cov.BranchRate, cov.Complexity = round(cov.LineRate), round(cov.BranchRate), round(cov.Complexity)
+	for _, pkg := range cov.Packages {
+		sort.SliceStable(pkg.Classes, func(i, j int) bool {
+			ci, cj := pkg.Classes[i], pkg.Classes[j]
+			if ci.Filename != cj.Filename {
+				return ci.Filename < cj.Filename
+			}
+			return ci.Name < cj.Name
+		})
+		pkg.LineRate, pkg.BranchRate, pkg.Complexity = round(pkg.LineRate), round(pkg.BranchRate), round(pkg.Complexity)
+		for _, class := range pkg.Classes {
+			sort.SliceStable(class.Methods, func(i, j int) bool {
+				return class.Methods[i].Name < class.Methods[j].Name
+			})
+			class.Lines.sort()
+			class.LineRate, class.BranchRate, class.Complexity = round(class.LineRate), round(class.BranchRate), round(class.Complexity)
+			for _, m := range class.Methods {
+				m.Lines.sort()
+				m.LineRate, m.BranchRate, m.Complexity = round(m.LineRate), round(m.BranchRate), round(m.Complexity)
+			}
+		}
+	}
+}
+
+func (lines Lines) sort() {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Number < lines[j].Number
+	})
+}
+
+func round(f float32) float32 {
+	scale := math.Pow10(rateDigits)
+	return float32(math.Round(float64(f)*scale) / scale)
+}
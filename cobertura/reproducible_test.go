@@ -0,0 +1,54 @@
+package cobertura
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildReproducible(t *testing.T) {
+	f, err := os.Open("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	profiles, err := ParseProfiles(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed := []*Profile{profiles[1], profiles[0]}
+
+	defer os.Setenv(sourceDateEpoch, os.Getenv(sourceDateEpoch))
+	os.Setenv(sourceDateEpoch, "1500000000")
+	var out [2]bytes.Buffer
+	for i, ps := range [][]*Profile{profiles, reversed} {
+		cov, err := Build(context.Background(), ps, nil, Options{Strict: true, Reproducible: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cov.Timestamp != 1500000000000 {
+			t.Errorf("Got timestamp %d; want SOURCE_DATE_EPOCH", cov.Timestamp)
+		}
+		if err := cov.WriteXML(&out[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out[0].String() != out[1].String() {
+		t.Errorf("Output depends on profile order:\n%s\n%s", out[0].String(), out[1].String())
+	}
+	if !strings.Contains(out[0].String(), `complexity="1.3333"`) {
+		t.Errorf("Expected rounded complexity:\n%s", out[0].String())
+	}
+
+	cov, err := Build(context.Background(), profiles, nil, Options{Reproducible: true, Timestamp: time.Unix(1, 0)})
+	if err != nil || cov.Timestamp != 1000 {
+		t.Errorf("Expected the explicit timestamp to win; got %v, %v", cov, err)
+	}
+	os.Unsetenv(sourceDateEpoch)
+	if _, err := Build(context.Background(), profiles, nil, Options{Reproducible: true}); err == nil {
+		t.Error("Expected error without timestamp")
+	}
+}
@@ -0,0 +1,131 @@
+package cobertura
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// ReadXML reads a Cobertura report, as written by WriteXML or other tools.
+func ReadXML(in io.Reader) (*Coverage, error) {
+	cov := &Coverage{}
+	if err := xml.NewDecoder(in).Decode(cov); err != nil {
+		return nil, err
+	}
+	return cov, nil
+}
+
+// Profiles rebuilds coverage profiles in the given mode from the line hits of
+// the report, for go tool cover and other profile tools.
+//
+// Cobertura only records lines, so every line with hits becomes a block of
+// its own that spans the text of the line and holds the statements starting
+// on it. Lines outside the source file or without text are left out. Source
+// files are looked up like in Build and then under the report's sources;
+// files that can't be found or parsed are handled according to opts.Strict.
+func (cov *Coverage) Profiles(mode string, opts Options) ([]*Profile, error) {
+	switch mode {
+	case "set", "count", "atomic":
+	default:
+		return nil, fmt.Errorf("unknown mode %q: want set, count or atomic", mode)
+	}
+	c, err := newConverter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]map[int]int64)
+	var names []string
+	for _, pkg := range cov.Packages {
+		for _, class := range pkg.Classes {
+			lines := hits[class.Filename]
+			if lines == nil {
+				lines = make(map[int]int64)
+				hits[class.Filename] = lines
+				names = append(names, class.Filename)
+			}
+			add := func(ls Lines) {
+				for _, l := range ls {
+					if h, ok := lines[l.Number]; !ok || l.Hits > h {
+						lines[l.Number] = l.Hits
+					}
+				}
+			}
+			add(class.Lines)
+			for _, m := range class.Methods {
+				add(m.Lines)
+			}
+		}
+	}
+
+	files := make(map[string]*Profile)
+	var diags Diagnostics
+	for _, name := range names {
+		p, err := c.lineProfile(cov.Sources, name, mode, hits[name])
+		if err != nil {
+			diags = append(diags, err.(Diagnostic))
+			continue
+		}
+		files[name] = p
+	}
+	if err := opts.report(diags); err != nil {
+		return nil, err
+	}
+	return sortedProfiles(files), nil
+}
+
+// lineProfile returns the profile of a file with one block per line with
+// hits. Errors are of type Diagnostic.
+func (c *converter) lineProfile(sources []*Source, fileName, mode string, hits map[int]int64) (*Profile, error) {
+	file, err := findFile(c.dir, fileName)
+	if err != nil {
+		for _, s := range sources {
+			if f := filepath.Join(s.Path, fileName); exists(f) {
+				file, err = f, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, Diagnostic{Kind: UnresolvedFile, File: fileName, Err: err}
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, Diagnostic{Kind: UnparseableFile, File: fileName, Err: err}
+	}
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, data, 0)
+	if err != nil {
+		return nil, Diagnostic{Kind: UnparseableFile, File: fileName, Err: err}
+	}
+	stmts := statementLines(fset, parsed)
+
+	text := bytes.Split(data, []byte("\n"))
+	p := &Profile{FileName: fileName, Mode: mode}
+	for number, h := range hits {
+		if number < 1 || number > len(text) {
+			continue
+		}
+		line := text[number-1]
+		start := len(line) - len(bytes.TrimLeft(line, " \t")) + 1
+		end := len(bytes.TrimRight(line, " \t\r")) + 1
+		if end <= start {
+			continue
+		}
+		count := int(h)
+		if mode == "set" {
+			count = setCount(count)
+		}
+		p.Blocks = append(p.Blocks, ProfileBlock{
+			StartLine: number, StartCol: start,
+			EndLine: number, EndCol: end,
+			NumStmt: stmts[number], Count: count,
+		})
+	}
+	return p, nil
+}
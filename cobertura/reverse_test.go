@@ -0,0 +1,81 @@
+package cobertura
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProfilesFromXML(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_branches.txt", Options{})
+	var buf bytes.Buffer
+	if err := cov.WriteXML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	cov, err := ReadXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := cov.Profiles("count", Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || profiles[0].FileName != "./testdata/branches.go" || profiles[0].Mode != "count" {
+		t.Fatalf("Unexpected profiles: %+v", profiles)
+	}
+
+	stmts := 0
+	blocks := make(map[int]ProfileBlock)
+	for _, b := range profiles[0].Blocks {
+		stmts += b.NumStmt
+		blocks[b.StartLine] = b
+	}
+	if stmts != 13 {
+		t.Errorf("Expected the 13 statements of the source; got %d", stmts)
+	}
+	want := map[int]ProfileBlock{
+		4:  {StartLine: 4, StartCol: 2, EndLine: 4, EndCol: 22, NumStmt: 1, Count: 2},
+		6:  {StartLine: 6, StartCol: 2, EndLine: 6, EndCol: 20, NumStmt: 1, Count: 0},
+		9:  {StartLine: 9, StartCol: 3, EndLine: 9, EndCol: 8, NumStmt: 1, Count: 0},
+		23: {StartLine: 23, StartCol: 3, EndLine: 23, EndCol: 11, NumStmt: 1, Count: 0},
+	}
+	for line, b := range want {
+		if blocks[line] != b {
+			t.Errorf("Line %d: got %+v; want %+v", line, blocks[line], b)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteProfiles(&buf, profiles); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "mode: count\n./testdata/branches.go:4.2,4.22 1 2\n./testdata/branches.go:5.3,5.6 1 2\n") {
+		t.Errorf("Unexpected profile:\n%s", buf.String())
+	}
+	if _, err := ParseProfiles(&buf); err != nil {
+		t.Errorf("Written profile doesn't parse: %v", err)
+	}
+}
+
+func TestProfilesUnresolvedFile(t *testing.T) {
+	cov := &Coverage{Packages: []*Package{{Classes: []*Class{{Filename: "does-not-exist.go", Lines: Lines{{Number: 1, Hits: 1}}}}}}}
+	if _, err := cov.Profiles("set", Options{Strict: true}); err == nil {
+		t.Error("Expected diagnostics for an unresolved file")
+	}
+	if _, err := cov.Profiles("bogus", Options{}); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestProfilesSetMode(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_branches.txt", Options{})
+	profiles, err := cov.Profiles("set", Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range profiles[0].Blocks {
+		if b.Count > 1 {
+			t.Errorf("Got count %d in set mode at line %d", b.Count, b.StartLine)
+		}
+	}
+}
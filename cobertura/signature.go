@@ -0,0 +1,289 @@
+package cobertura
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SignatureStyle selects how Method.Signature is rendered.
+type SignatureStyle int
+
+const (
+	// GoSignature renders the Go declaration without its body, e.g.
+	// "func (l *List[T]) Push(v T) error".
+	GoSignature SignatureStyle = iota
+	// JVMSignature renders a JVM method descriptor, e.g.
+	// "(Ljava/lang/Object;)Ljava/lang/Throwable;", for tools that parse it.
+	JVMSignature
+)
+
+// ParseSignatureStyle parses "go" or "jvm".
+func ParseSignatureStyle(s string) (SignatureStyle, error) {
+	switch s {
+	case "go":
+		return GoSignature, nil
+	case "jvm":
+		return JVMSignature, nil
+	}
+	return 0, fmt.Errorf("unknown signature style %q: want go or jvm", s)
+}
+
+func (s SignatureStyle) String() string {
+	switch s {
+	case GoSignature:
+		return "go"
+	case JVMSignature:
+		return "jvm"
+	}
+	return fmt.Sprintf("SignatureStyle(%d)", int(s))
+}
+
+// TypeParamStyle selects how the type parameters of generic receiver types
+// are rendered in class names.
+type TypeParamStyle int
+
+const (
+	// RuntimeTypeParams renders them like runtime symbol names, e.g.
+	// "List[...]", keeping generic types apart from plain ones.
+	RuntimeTypeParams TypeParamStyle = iota
+	// BareTypeParams leaves them out, e.g. "List".
+	BareTypeParams
+)
+
+// ParseTypeParamStyle parses "runtime" or "bare".
+func ParseTypeParamStyle(s string) (TypeParamStyle, error) {
+	switch s {
+	case "runtime":
+		return RuntimeTypeParams, nil
+	case "bare":
+		return BareTypeParams, nil
+	}
+	return 0, fmt.Errorf("unknown type parameter style %q: want runtime or bare", s)
+}
+
+func (s TypeParamStyle) String() string {
+	switch s {
+	case RuntimeTypeParams:
+		return "runtime"
+	case BareTypeParams:
+		return "bare"
+	}
+	return fmt.Sprintf("TypeParamStyle(%d)", int(s))
+}
+
+// signature renders the signature of n in the configured style.
+func (v *fileVisitor) signature(n *ast.FuncDecl) string {
+	if v.opts.Signature == JVMSignature {
+		return v.descriptor(n)
+	}
+	return goSignature(n)
+}
+
+// varSignature renders the signature of the pseudo method for the
+// initializer of a package-level variable, e.g. "var handler http.HandlerFunc".
+func (v *fileVisitor) varSignature(name string, typ ast.Expr) string {
+	if v.opts.Signature == JVMSignature {
+		return "()V"
+	}
+	if typ == nil {
+		return "var " + name
+	}
+	return "var " + name + " " + types.ExprString(typ)
+}
+
+// litSignature renders the signature of a function literal, e.g.
+// "func(i, j int) bool".
+func (v *fileVisitor) litSignature(lit *ast.FuncLit) string {
+	if v.opts.Signature == JVMSignature {
+		return v.descriptor(&ast.FuncDecl{Name: ast.NewIdent("func"), Type: lit.Type})
+	}
+	return types.ExprString(lit.Type)
+}
+
+// goSignature renders a function declaration without its body on one line.
+func goSignature(n *ast.FuncDecl) string {
+	var buf bytes.Buffer
+	buf.WriteString("func ")
+	if n.Recv != nil {
+		fmt.Fprintf(&buf, "(%s) ", fieldList(n.Recv))
+	}
+	buf.WriteString(n.Name.Name)
+	if n.Type.TypeParams != nil {
+		fmt.Fprintf(&buf, "[%s]", fieldList(n.Type.TypeParams))
+	}
+	fmt.Fprintf(&buf, "(%s)", fieldList(n.Type.Params))
+	if res := n.Type.Results; res != nil && len(res.List) > 0 {
+		if len(res.List) == 1 && len(res.List[0].Names) == 0 {
+			fmt.Fprintf(&buf, " %s", types.ExprString(res.List[0].Type))
+		} else {
+			fmt.Fprintf(&buf, " (%s)", fieldList(res))
+		}
+	}
+	return buf.String()
+}
+
+func fieldList(fl *ast.FieldList) string {
+	if fl == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(fl.List))
+	for _, f := range fl.List {
+		typ := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			parts = append(parts, typ)
+			continue
+		}
+		names := make([]string, len(f.Names))
+		for i, name := range f.Names {
+			names[i] = name.Name
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+typ)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// jvmTypes maps predeclared Go types to JVM field descriptors.
+var jvmTypes = map[string]string{
+	"bool":    "Z",
+	"byte":    "B",
+	"int8":    "B",
+	"uint8":   "B",
+	"int16":   "S",
+	"uint16":  "C",
+	"int32":   "I",
+	"uint32":  "I",
+	"rune":    "I",
+	"int":     "J",
+	"uint":    "J",
+	"int64":   "J",
+	"uint64":  "J",
+	"uintptr": "J",
+	"float32": "F",
+	"float64": "D",
+	"string":  "Ljava/lang/String;",
+	"error":   "Ljava/lang/Throwable;",
+}
+
+const jvmObject = "Ljava/lang/Object;"
+
+// descriptor renders a JVM method descriptor for n. Named types become
+// classes named by their import path, type parameters are erased to Object
+// and multiple results are returned as an Object array.
+func (v *fileVisitor) descriptor(n *ast.FuncDecl) string {
+	typeParams := make(map[string]bool)
+	addTypeParams := func(fl *ast.FieldList) {
+		if fl == nil {
+			return
+		}
+		for _, f := range fl.List {
+			for _, name := range f.Names {
+				typeParams[name.Name] = true
+			}
+		}
+	}
+	addTypeParams(n.Type.TypeParams)
+	if n.Recv != nil {
+		recv := n.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		switch r := recv.(type) {
+		case *ast.IndexExpr:
+			if id, ok := r.Index.(*ast.Ident); ok {
+				typeParams[id.Name] = true
+			}
+		case *ast.IndexListExpr:
+			for _, x := range r.Indices {
+				if id, ok := x.(*ast.Ident); ok {
+					typeParams[id.Name] = true
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	for _, f := range n.Type.Params.List {
+		d := v.typeDescriptor(f.Type, typeParams)
+		buf.WriteString(d)
+		for i := 1; i < len(f.Names); i++ {
+			buf.WriteString(d)
+		}
+	}
+	buf.WriteByte(')')
+	var results []*ast.Field
+	if n.Type.Results != nil {
+		results = n.Type.Results.List
+	}
+	switch {
+	case len(results) == 0:
+		buf.WriteByte('V')
+	case len(results) == 1 && len(results[0].Names) <= 1:
+		buf.WriteString(v.typeDescriptor(results[0].Type, typeParams))
+	default:
+		buf.WriteString("[" + jvmObject)
+	}
+	return buf.String()
+}
+
+func (v *fileVisitor) typeDescriptor(expr ast.Expr, typeParams map[string]bool) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if typeParams[t.Name] || t.Name == "any" {
+			return jvmObject
+		}
+		if d, ok := jvmTypes[t.Name]; ok {
+			return d
+		}
+		return "L" + strings.TrimPrefix(v.pkg.Name, "./") + "/" + t.Name + ";"
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			if p, ok := v.imports[x.Name]; ok {
+				return "L" + p + "/" + t.Sel.Name + ";"
+			}
+		}
+	case *ast.StarExpr:
+		return v.typeDescriptor(t.X, typeParams)
+	case *ast.ParenExpr:
+		return v.typeDescriptor(t.X, typeParams)
+	case *ast.ArrayType:
+		return "[" + v.typeDescriptor(t.Elt, typeParams)
+	case *ast.Ellipsis:
+		return "[" + v.typeDescriptor(t.Elt, typeParams)
+	case *ast.MapType:
+		return "Ljava/util/Map;"
+	case *ast.IndexExpr:
+		return v.typeDescriptor(t.X, typeParams)
+	case *ast.IndexListExpr:
+		return v.typeDescriptor(t.X, typeParams)
+	}
+	return jvmObject
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// fileImports maps the names a file uses for its imports to their paths.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(p)
+		if majorVersion.MatchString(name) && path.Dir(p) != "." {
+			name = path.Base(path.Dir(p))
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = p
+	}
+	return imports
+}
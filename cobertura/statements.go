@@ -0,0 +1,68 @@
+package cobertura
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// statements returns the start positions of the statements of f, sorted, the
+// way cmd/cover counts the statements of a block: those in statement lists,
+// but not the init and post statements of if, for and switch. An if in an
+// else counts as a statement of its own.
+func statements(fset *token.FileSet, f *ast.File) []token.Position {
+	var stmts []token.Position
+	add := func(list []ast.Stmt) {
+		for _, s := range list {
+			switch s.(type) {
+			case *ast.CaseClause, *ast.CommClause, *ast.EmptyStmt:
+				continue
+			}
+			stmts = append(stmts, fset.Position(s.Pos()))
+		}
+	}
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BlockStmt:
+			add(n.List)
+		case *ast.CaseClause:
+			add(n.Body)
+		case *ast.CommClause:
+			add(n.Body)
+		case *ast.IfStmt:
+			if elseIf, ok := n.Else.(*ast.IfStmt); ok {
+				add([]ast.Stmt{elseIf})
+			}
+		}
+		return true
+	})
+	sort.Slice(stmts, func(i, j int) bool {
+		return after(stmts[j].Line, stmts[j].Column, stmts[i].Line, stmts[i].Column)
+	})
+	return stmts
+}
+
+// statementLines counts the statements starting on each line of f.
+func statementLines(fset *token.FileSet, f *ast.File) map[int]int {
+	counts := make(map[int]int)
+	for _, p := range statements(fset, f) {
+		counts[p.Line]++
+	}
+	return counts
+}
+
+// blockLines returns the lines on which statements of b start, in order. Lines
+// holding only braces, comments or parts of statements started further up
+// aren't executable, so they aren't reported.
+func (v *fileVisitor) blockLines(b ProfileBlock) []int {
+	i := sort.Search(len(v.stmts), func(i int) bool {
+		return !after(b.StartLine, b.StartCol, v.stmts[i].Line, v.stmts[i].Column)
+	})
+	var lines []int
+	for ; i < len(v.stmts) && after(b.EndLine, b.EndCol, v.stmts[i].Line, v.stmts[i].Column); i++ {
+		if n := len(lines); n == 0 || lines[n-1] != v.stmts[i].Line {
+			lines = append(lines, v.stmts[i].Line)
+		}
+	}
+	return lines
+}
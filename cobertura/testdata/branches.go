@@ -0,0 +1,27 @@
+package testdata
+
+func Branches(x int, c chan int) int {
+	if x > 0 && x < 10 {
+		x++
+	} else if x < -5 {
+		x--
+	} else {
+		x = 0
+	}
+	switch x {
+	case 1:
+		x = 2
+	case 2:
+	default:
+		x = 3
+	}
+	for i := 0; i < x; i++ {
+		x--
+	}
+	select {
+	case v := <-c:
+		return v
+	default:
+	}
+	return x
+}
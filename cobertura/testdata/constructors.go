@@ -0,0 +1,39 @@
+package testdata
+
+import "errors"
+
+type Server struct {
+	addr string
+}
+
+func NewServer(addr string) *Server {
+	return &Server{addr: addr}
+}
+
+func newServerWithOpts(addr string, opts ...string) (*Server, error) {
+	if len(opts) > 1 {
+		return nil, errors.New("too many options")
+	}
+	return NewServer(addr), nil
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+type Box[T any] struct {
+	v T
+}
+
+func NewBox[T any](v T) Box[T] {
+	return Box[T]{v: v}
+}
+
+func New[T any]() T {
+	var zero T
+	return zero
+}
+
+func newline() string {
+	return "\n"
+}
@@ -0,0 +1,18 @@
+package testdata
+
+//coverage:ignore unreachable on supported platforms
+func Ignored() int {
+	return 1
+}
+
+func Region(x int) int {
+	if x > 0 {
+		return x
+	}
+	//coverage:ignore-start
+	if x < -100 {
+		panic("impossible")
+	}
+	//coverage:ignore-end
+	return -x
+}
@@ -0,0 +1,16 @@
+package testdata
+
+import "sort"
+
+func Sorted(xs []int, done chan bool) []int {
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	go func() {
+		defer func() {
+			done <- true
+		}()
+		if len(xs) > 100 {
+			panic("too many")
+		}
+	}()
+	return xs
+}
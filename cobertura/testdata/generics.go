@@ -0,0 +1,32 @@
+package testdata
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+func (l List[K]) Len() int {
+	return len(l.items)
+}
+
+func (l ( * List[E] )) Reset() {
+	l.items = nil
+}
+
+type Pair[K comparable, V any] struct {
+	k K
+	v V
+}
+
+func (p Pair[A, B]) Key() A {
+	return p.k
+}
+
+type Plain struct{}
+
+func (p *  Plain) Name() string {
+	return "plain"
+}
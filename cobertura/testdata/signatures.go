@@ -0,0 +1,24 @@
+package testdata
+
+import (
+	"io"
+	str "strings"
+)
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T, more ...T) error {
+	l.items = append(l.items, v)
+	l.items = append(l.items, more...)
+	return nil
+}
+
+func Map[K comparable, V any](m map[K]V, w io.Writer) (n int, err error) {
+	return len(m), nil
+}
+
+func Join(a, b string, r *str.Reader) string {
+	return a + b
+}
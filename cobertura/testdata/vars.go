@@ -0,0 +1,42 @@
+package testdata
+
+import "strings"
+
+type route struct {
+	path    string
+	handler func(string) string
+}
+
+var Upper = func(s string) string {
+	return strings.ToUpper(s)
+}
+
+var routes = []route{
+	{"/a", func(s string) string { return s }},
+	{"/b", func(s string) string {
+		if s == "" {
+			return "empty"
+		}
+		return s + s
+	}},
+}
+
+var _ = register(func() {
+	routes = append(routes, route{})
+})
+
+var plain = strings.Repeat("x", 2)
+
+func register(f func()) bool {
+	f()
+	return true
+}
+
+func Route(path, s string) string {
+	for _, r := range routes {
+		if r.path == path {
+			return r.handler(s)
+		}
+	}
+	return plain
+}
@@ -0,0 +1,148 @@
+package cobertura
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/tabwriter"
+)
+
+// ThresholdScope selects what a Threshold applies to.
+type ThresholdScope int
+
+const (
+	// TotalScope applies to the whole report.
+	TotalScope ThresholdScope = iota
+	// PackageScope applies to each package whose name matches the pattern.
+	PackageScope
+	// FileScope applies to each source file whose name matches the pattern.
+	FileScope
+)
+
+func (s ThresholdScope) String() string {
+	switch s {
+	case TotalScope:
+		return "total"
+	case PackageScope:
+		return "package"
+	case FileScope:
+		return "file"
+	}
+	return fmt.Sprintf("ThresholdScope(%d)", int(s))
+}
+
+// Threshold is a minimum line rate.
+type Threshold struct {
+	Scope   ThresholdScope
+	Pattern string  // path.Match pattern on Package.Name or Class.Filename; unused for TotalScope
+	Min     float32 // from 0.0 to 1.0
+}
+
+// Violation is a package, file or the whole report below a threshold.
+type Violation struct {
+	Threshold Threshold
+	Name      string // package or file name; empty for TotalScope
+	Rate      float32
+}
+
+// Violations is a list of thresholds that were not met. As an error it renders
+// them as a table.
+type Violations []Violation
+
+func (vs Violations) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d coverage threshold(s) not met:\n", len(vs))
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "\tSCOPE\tNAME\tCOVERAGE\tMINIMUM\n")
+	for _, v := range vs {
+		name := v.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "\t%s\t%s\t%.1f%%\t%.1f%%\n", v.Threshold.Scope, name, v.Rate*100, v.Threshold.Min*100)
+	}
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// CheckThresholds compares the line rates of the report, in its Metric, with
+// thresholds and returns the Violations, or nil if all are met. Every
+// threshold is checked on its own, so a package matched by several patterns
+// must meet all of them. A file's rate is that of all classes in it. Patterns
+// are matched as by path.Match, so * does not match across slashes.
+func (cov *Coverage) CheckThresholds(thresholds []Threshold) error {
+	if err := checkPatterns(thresholds); err != nil {
+		return err
+	}
+	var vs Violations
+	for _, t := range thresholds {
+		switch t.Scope {
+		case TotalScope:
+			if rate := cov.Metric.rate(cov); rate < t.Min {
+				vs = append(vs, Violation{Threshold: t, Rate: rate})
+			}
+		case PackageScope:
+			for _, pkg := range cov.Packages {
+				if !matchPattern(t.Pattern, pkg.Name) {
+					continue
+				}
+				if rate := cov.Metric.rate(pkg); rate < t.Min {
+					vs = append(vs, Violation{Threshold: t, Name: pkg.Name, Rate: rate})
+				}
+			}
+		case FileScope:
+			for _, f := range cov.files() {
+				if !matchPattern(t.Pattern, f.Filename) {
+					continue
+				}
+				if rate := cov.Metric.rate(f); rate < t.Min {
+					vs = append(vs, Violation{Threshold: t, Name: f.Filename, Rate: rate})
+				}
+			}
+		}
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return vs
+}
+
+// checkPatterns returns an error for the first invalid pattern of thresholds.
+func checkPatterns(thresholds []Threshold) error {
+	for _, t := range thresholds {
+		if _, err := path.Match(t.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid %s threshold pattern %q: %v", t.Scope, t.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// files returns one class per source file holding the methods and lines of
+// all classes in it, in the order of the report. Lines are merged by number,
+// so a line shared by classes of the file counts once.
+func (cov *Coverage) files() []*Class {
+	var files []*Class
+	byName := make(map[string]*Class)
+	for _, pkg := range cov.Packages {
+		for _, class := range pkg.Classes {
+			f := byName[class.Filename]
+			if f == nil {
+				f = &Class{Filename: class.Filename}
+				byName[class.Filename] = f
+				files = append(files, f)
+			}
+			f.Methods = append(f.Methods, class.Methods...)
+			for _, line := range class.Lines {
+				f.Lines.merge(line)
+			}
+		}
+	}
+	return files
+}
+
+// matchPattern reports whether name matches the path.Match pattern.
+func matchPattern(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,100 @@
+package cobertura
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	cov := buildTestdata(t, "testdata/testdata_set.txt", Options{})
+	err := cov.CheckThresholds([]Threshold{
+		{Scope: TotalScope, Min: 0.4},
+		{Scope: PackageScope, Pattern: "./test*", Min: 0.8},
+		{Scope: PackageScope, Pattern: "other/*", Min: 1},
+		{Scope: FileScope, Pattern: "*/*/func*.go", Min: 0.6},
+	})
+	vs, ok := err.(Violations)
+	if !ok || len(vs) != 2 {
+		t.Fatalf("Expected 2 violations; got %v", err)
+	}
+	if v := vs[0]; v.Threshold.Scope != PackageScope || v.Name != "./testdata" {
+		t.Errorf("Unexpected violation %+v", v)
+	}
+	if v := vs[1]; v.Threshold.Scope != FileScope || v.Name != "./testdata/func1.go" || v.Rate != 0.5 {
+		t.Errorf("Unexpected violation %+v", v)
+	}
+	if s := err.Error(); !strings.Contains(s, "./testdata/func1.go  50.0%     60.0%") {
+		t.Errorf("Unexpected table:\n%s", s)
+	}
+
+	if err := cov.CheckThresholds([]Threshold{{Scope: TotalScope, Min: 0.4}}); err != nil {
+		t.Errorf("Expected no violations; got %v", err)
+	}
+	if err := cov.CheckThresholds([]Threshold{{Scope: FileScope, Pattern: "[", Min: 0.4}}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestConvertThresholds(t *testing.T) {
+	f, err := os.Open("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	err = Convert(context.Background(), f, &out, Options{Thresholds: []Threshold{{Scope: TotalScope, Min: 1}}})
+	if _, ok := err.(Violations); !ok {
+		t.Errorf("Expected Violations; got %v", err)
+	}
+	if !strings.Contains(out.String(), "<coverage") {
+		t.Error("Expected the report to be written")
+	}
+}
+
+func TestFileThresholdsTypeClasses(t *testing.T) {
+	var profiles []*Profile
+	for _, name := range []string{"testdata/testdata_generics.txt", "testdata/testdata_signatures.txt"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps, _, err := ReadProfiles(f, name)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		profiles = append(profiles, ps...)
+	}
+	cov, err := Build(context.Background(), profiles, nil, Options{Strict: true, Grouping: TypeClasses})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// List has methods in both files; each file is checked on its own lines.
+	err = cov.CheckThresholds([]Threshold{{Scope: FileScope, Pattern: "*/*/*.go", Min: 0.5}})
+	vs, ok := err.(Violations)
+	if !ok || len(vs) != 1 {
+		t.Fatalf("Expected 1 violation; got %v", err)
+	}
+	if v := vs[0]; v.Name != "./testdata/generics.go" || v.Rate != 0.4 {
+		t.Errorf("Unexpected violation %+v", v)
+	}
+}
+
+func TestConvertInvalidThresholdPattern(t *testing.T) {
+	f, err := os.Open("testdata/testdata_set.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	err = Convert(context.Background(), f, &out, Options{Thresholds: []Threshold{{Scope: PackageScope, Pattern: "[", Min: 1}}})
+	if err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+	if out.Len() > 0 {
+		t.Error("Expected no report to be written")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/t-yuki/gocover-cobertura
+
+go 1.18
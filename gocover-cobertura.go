@@ -1,176 +1,322 @@
 package main
 
 import (
-	"encoding/xml"
+	"context"
+	"flag"
 	"fmt"
-	"go/ast"
-	"go/build"
-	"go/parser"
-	"go/token"
 	"io"
-	"io/ioutil"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/t-yuki/gocover-cobertura/cobertura"
 )
 
-const coberturaDTDDecl = "<!DOCTYPE coverage SYSTEM \"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n"
+var (
+	outputFile = flag.String("o", "", "write the report to `file` instead of standard output")
+	workDir    = flag.String("C", "", "change to `dir` before reading profiles and resolving source files")
+	timestamp  = flag.String("timestamp", "", "report `time` as Unix seconds or RFC 3339 instead of the current time")
+	strict     = flag.Bool("strict", false, "fail if a profile line or source file can't be converted instead of warning")
+	signature  = flag.String("signature", "go", "render method signatures in `style` go or jvm (descriptors)")
+	typeParams = flag.String("typeparams", "runtime", "name classes of generic types in `style` runtime (List[...]) or bare (List)")
+	grouping   = flag.String("classes", "receiver", "group functions into a class per `kind` receiver (type and file), file or type (listed together across files)")
+	funcClass  = flag.String("funcclass", "dash", "put functions without receiver in the class `style` dash (-), file or constructor")
+	metric     = flag.String("metric", "line", "compute line rates by `metric` line or statement (like go test -cover)")
+	reverse    = flag.Bool("reverse", false, "read Cobertura XML reports and write a coverage profile")
+	coverMode  = flag.String("covermode", "count", "write the profile in `mode` set, count or atomic with -reverse")
+	funcLits   = flag.Bool("funclits", false, "report function literals as methods of their own, e.g. Outer.func1")
+	reproduce  = flag.Bool("reproducible", false, "write the same report for the same input, timed by -timestamp or SOURCE_DATE_EPOCH")
+	relative   = flag.Bool("relative", false, "name files relative to the module root or the -source roots")
+	skipGen    = flag.Bool("skip-generated", false, "leave out files marked // Code generated ... DO NOT EDIT.")
+	thresholds []cobertura.Threshold
+	include    patternsFlag
+	exclude    patternsFlag
+	symbols    patternsFlag
+	sources    patternsFlag
+	rewrites   rewritesFlag
+)
 
-func main() {
-	convert(os.Stdin, os.Stdout)
+func init() {
+	flag.Var(thresholdFlag{cobertura.TotalScope}, "min", "fail if total line coverage is below `percent`")
+	flag.Var(thresholdFlag{cobertura.PackageScope}, "min-package", "fail if packages matching the glob in `glob=percent` are below percent; repeatable")
+	flag.Var(thresholdFlag{cobertura.FileScope}, "min-file", "fail if files matching the glob in `glob=percent` are below percent; repeatable")
+	flag.Var(&include, "include", "convert only files whose name or package matches `pattern` (glob, or regexp after re:); repeatable")
+	flag.Var(&exclude, "exclude", "leave out files whose name or package matches `pattern` (glob, or regexp after re:); repeatable")
+	flag.Var(&sources, "source", "list `dir` as source root instead of the module or GOPATH directories; repeatable")
+	flag.Var(&rewrites, "rewrite", "replace the leading path `from=to` of file names in the profile; repeatable")
+	flag.Var(&symbols, "exclude-symbol", "leave out functions matching `qualifier.name`, e.g. *.String, (*Mock*).* or main.main; repeatable")
 }
 
-func convert(in io.Reader, out io.Writer) {
-	profiles, err := ParseProfiles(in)
-	if err != nil {
-		panic("Can't parse profiles")
-	}
-
-	srcDirs := build.Default.SrcDirs()
-	sources := make([]*Source, len(srcDirs))
-	for i, dir := range srcDirs {
-		sources[i] = &Source{dir}
-	}
-
-	coverage := Coverage{Sources: sources, Packages: nil, Timestamp: time.Now().UnixNano() / int64(time.Millisecond)}
-	coverage.parseProfiles(profiles)
-
-	fmt.Fprintf(out, xml.Header)
-	fmt.Fprintf(out, coberturaDTDDecl)
-
-	encoder := xml.NewEncoder(out)
-	encoder.Indent("", "\t")
-	err = encoder.Encode(coverage)
-	if err != nil {
-		panic(err)
-	}
+// Exit codes.
+const (
+	exitError       = 1 // conversion failed
+	exitUsage       = 2 // invalid command line; set by the flag package
+	exitDiagnostics = 3 // -strict and some input could not be converted
+	exitThresholds  = 4 // the report was written but coverage is below a -min threshold
+)
 
-	fmt.Fprintln(out)
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gocover-cobertura [flags] [profile ...]\n\n")
+	fmt.Fprintf(os.Stderr, "Converts go test -coverprofile output to Cobertura XML.\n")
+	fmt.Fprintf(os.Stderr, "Profiles are read from standard input if none are named.\n")
+	fmt.Fprintf(os.Stderr, "A directory is read as the GOCOVERDIR of a go build -cover program.\n")
+	fmt.Fprintf(os.Stderr, "With -reverse, reads Cobertura XML reports and writes a profile instead.\n\n")
+	flag.PrintDefaults()
 }
 
-func (cov *Coverage) parseProfiles(profiles []*Profile) error {
-	cov.Packages = []*Package{}
-	for _, profile := range profiles {
-		cov.parseProfile(profile)
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "gocover-cobertura: %v\n", err)
+		switch err.(type) {
+		case cobertura.Diagnostics:
+			os.Exit(exitDiagnostics)
+		case cobertura.Violations:
+			os.Exit(exitThresholds)
+		}
+		os.Exit(exitError)
 	}
-	cov.LinesValid = cov.NumLines()
-	cov.LinesCovered = cov.NumLinesWithHits()
-	cov.LineRate = cov.HitRate()
-	return nil
 }
 
-func (cov *Coverage) parseProfile(profile *Profile) error {
-	fileName := profile.FileName
-	absFilePath, err := findFile(fileName)
+func run(inputs []string) error {
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			return err
+		}
+	}
+	sig, err := cobertura.ParseSignatureStyle(*signature)
 	if err != nil {
 		return err
 	}
-	fset := token.NewFileSet()
-	parsed, err := parser.ParseFile(fset, absFilePath, nil, 0)
+	tps, err := cobertura.ParseTypeParamStyle(*typeParams)
 	if err != nil {
 		return err
 	}
-	data, err := ioutil.ReadFile(absFilePath)
+	group, err := cobertura.ParseClassGrouping(*grouping)
 	if err != nil {
 		return err
 	}
+	fcs, err := cobertura.ParseFuncClassStyle(*funcClass)
+	if err != nil {
+		return err
+	}
+	rm, err := cobertura.ParseRateMetric(*metric)
+	if err != nil {
+		return err
+	}
+	opts := cobertura.Options{
+		Strict:        *strict,
+		Warnings:      os.Stderr,
+		Signature:     sig,
+		TypeParams:    tps,
+		Grouping:      group,
+		FuncClass:     fcs,
+		Metric:        rm,
+		Include:       include,
+		Exclude:       exclude,
+		SkipGenerated: *skipGen,
 
-	pkgPath, _ := filepath.Split(fileName)
-	pkgPath = strings.TrimRight(pkgPath, string(os.PathSeparator))
-
-	var pkg *Package
-	for _, p := range cov.Packages {
-		if p.Name == pkgPath {
-			pkg = p
+		ExcludeSymbols: symbols,
+		Relative:       *relative,
+		Sources:        sources,
+		Rewrites:       rewrites,
+		FuncLits:       *funcLits,
+		Reproducible:   *reproduce,
+	}
+	if *timestamp != "" {
+		t, err := parseTimestamp(*timestamp)
+		if err != nil {
+			return err
 		}
+		opts.Timestamp = t
 	}
-	if pkg == nil {
-		pkg = &Package{Name: pkgPath, Classes: []*Class{}}
-		cov.Packages = append(cov.Packages, pkg)
+	if *reverse {
+		profiles, err := readReports(inputs, opts)
+		if err != nil {
+			return err
+		}
+		return writeOutput(func(w io.Writer) error {
+			return cobertura.WriteProfiles(w, profiles)
+		})
 	}
-	visitor := &fileVisitor{
-		fset:     fset,
-		fileName: fileName,
-		fileData: data,
-		classes:  make(map[string]*Class),
-		pkg:      pkg,
-		profile:  profile,
+	profiles, diags, err := readProfiles(inputs)
+	if err != nil {
+		return err
 	}
-	ast.Walk(visitor, parsed)
-	pkg.LineRate = pkg.HitRate()
-	return nil
+	cov, err := cobertura.Build(context.Background(), profiles, diags, opts)
+	if err != nil {
+		return err
+	}
+	if cov.LinesExcluded > 0 {
+		fmt.Fprintf(os.Stderr, "gocover-cobertura: %d line(s) excluded by coverage directives\n", cov.LinesExcluded)
+	}
+	if err := writeOutput(cov.WriteXML); err != nil {
+		return err
+	}
+	return cov.CheckThresholds(thresholds)
 }
 
-type fileVisitor struct {
-	fset     *token.FileSet
-	fileName string
-	fileData []byte
-	pkg      *Package
-	classes  map[string]*Class
-	profile  *Profile
+// writeOutput calls write with standard output or the -o file.
+func writeOutput(write func(io.Writer) error) error {
+	if *outputFile == "" {
+		return write(os.Stdout)
+	}
+	out, err := os.Create(*outputFile)
+	if err != nil {
+		return err
+	}
+	if err := write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
-func (v *fileVisitor) Visit(node ast.Node) ast.Visitor {
-	switch n := node.(type) {
-	case *ast.FuncDecl:
-		class := v.class(n)
-		method := v.method(n)
-		method.LineRate = method.Lines.HitRate()
-		class.Methods = append(class.Methods, method)
-		for _, line := range method.Lines {
-			class.Lines = append(class.Lines, line)
+// readReports reads the named Cobertura reports, or standard input if there
+// are none, and merges the profiles rebuilt from them.
+func readReports(inputs []string, opts cobertura.Options) ([]*cobertura.Profile, error) {
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	var all []*cobertura.Profile
+	for _, name := range inputs {
+		cov, err := readReport(name)
+		if err != nil {
+			return nil, err
 		}
-		class.LineRate = class.Lines.HitRate()
+		profiles, err := cov.Profiles(*coverMode, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = cobertura.MergeProfiles(all, profiles)
 	}
-	return v
+	return all, nil
 }
 
-func (v *fileVisitor) method(n *ast.FuncDecl) *Method {
-	method := &Method{Name: n.Name.Name}
-	method.Lines = []*Line{}
-
-	start := v.fset.Position(n.Pos())
-	end := v.fset.Position(n.End())
-	startLine := start.Line
-	startCol := start.Column
-	endLine := end.Line
-	endCol := end.Column
-	// The blocks are sorted, so we can stop counting as soon as we reach the end of the relevant block.
-	for _, b := range v.profile.Blocks {
-		if b.StartLine > endLine || (b.StartLine == endLine && b.StartCol >= endCol) {
-			// Past the end of the function.
-			break
+func readReport(name string) (*cobertura.Coverage, error) {
+	in := os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
 		}
-		if b.EndLine < startLine || (b.EndLine == startLine && b.EndCol <= startCol) {
-			// Before the beginning of the function
-			continue
+		defer f.Close()
+		in = f
+	}
+	cov, err := cobertura.ReadXML(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return cov, nil
+}
+
+// readProfiles parses the named profiles, or standard input if there are none,
+// and merges them into one list.
+func readProfiles(inputs []string) ([]*cobertura.Profile, cobertura.Diagnostics, error) {
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	var all []*cobertura.Profile
+	var diags cobertura.Diagnostics
+	for _, name := range inputs {
+		profiles, more, err := readProfile(name)
+		if err != nil {
+			return nil, nil, err
 		}
-		for i := b.StartLine; i <= b.EndLine; i++ {
-			method.Lines.AddOrUpdateLine(i, int64(b.Count))
+		all = cobertura.MergeProfiles(all, profiles)
+		diags = append(diags, more...)
+	}
+	return all, diags, nil
+}
+
+func readProfile(name string) ([]*cobertura.Profile, cobertura.Diagnostics, error) {
+	if name == "-" {
+		return cobertura.ReadProfiles(os.Stdin, "<stdin>")
+	}
+	if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+		profiles, err := cobertura.ReadCoverDir(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", name, err)
 		}
+		return profiles, nil, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	profiles, diags, err := cobertura.ReadProfiles(f, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return profiles, diags, nil
+}
+
+// patternsFlag collects the values of a repeatable flag.
+type patternsFlag []string
+
+func (f *patternsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *patternsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// rewritesFlag collects the rewrite rules of a repeatable flag.
+type rewritesFlag []cobertura.Rewrite
+
+func (f *rewritesFlag) String() string { return "" }
+
+func (f *rewritesFlag) Set(s string) error {
+	r, err := cobertura.ParseRewrite(s)
+	if err != nil {
+		return err
 	}
-	return method
+	*f = append(*f, r)
+	return nil
+}
+
+// thresholdFlag adds a threshold of its scope to thresholds for each use of
+// the flag. Package and file thresholds are given as glob=percent.
+type thresholdFlag struct {
+	scope cobertura.ThresholdScope
 }
 
-func (v *fileVisitor) class(n *ast.FuncDecl) *Class {
-	className := v.recvName(n)
-	var class *Class = v.classes[className]
-	if class == nil {
-		class = &Class{Name: className, Filename: v.fileName, Methods: []*Method{}, Lines: []*Line{}}
-		v.classes[className] = class
-		v.pkg.Classes = append(v.pkg.Classes, class)
+func (f thresholdFlag) String() string { return "" }
+
+func (f thresholdFlag) Set(s string) error {
+	t := cobertura.Threshold{Scope: f.scope}
+	percent := s
+	if f.scope != cobertura.TotalScope {
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return fmt.Errorf("want glob=percent")
+		}
+		t.Pattern, percent = s[:i], s[i+1:]
+		if _, err := path.Match(t.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", t.Pattern, err)
+		}
+	}
+	min, err := strconv.ParseFloat(strings.TrimSuffix(percent, "%"), 32)
+	if err != nil || min < 0 || min > 100 {
+		return fmt.Errorf("invalid percentage %q", percent)
 	}
-	return class
+	t.Min = float32(min / 100)
+	thresholds = append(thresholds, t)
+	return nil
 }
 
-func (v *fileVisitor) recvName(n *ast.FuncDecl) string {
-	if n.Recv == nil {
-		return "-"
+// parseTimestamp parses a time given as Unix seconds or in RFC 3339 format.
+func parseTimestamp(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: want Unix seconds or RFC 3339", s)
 	}
-	recv := n.Recv.List[0].Type
-	start := v.fset.Position(recv.Pos())
-	end := v.fset.Position(recv.End())
-	name := string(v.fileData[start.Offset:end.Offset])
-	return strings.TrimSpace(strings.TrimLeft(name, "*"))
+	return t, nil
 }
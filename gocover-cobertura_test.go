@@ -2,19 +2,14 @@ package main
 
 import (
 	"encoding/xml"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-)
-
-const SaveTestResults = false
 
-type dirInfo struct {
-	PkgPath string
-}
+	"github.com/t-yuki/gocover-cobertura/cobertura"
+)
 
 func TestMain(t *testing.T) {
 	fname := filepath.Join(os.TempDir(), "stdout")
@@ -29,190 +24,87 @@ func TestMain(t *testing.T) {
 	if !strings.Contains(outputString, xml.Header) {
 		t.Fail()
 	}
-	if !strings.Contains(outputString, coberturaDTDDecl) {
+	if !strings.Contains(outputString, cobertura.DocType) {
 		t.Fail()
 	}
 }
 
-func TestConvertParseProfilesError(t *testing.T) {
-	defer func() {
-		r := recover()
-		if r == nil || r != "Can't parse profiles" {
-			t.Errorf("The code did not panic as expected; r = %+v", r)
-		}
-	}()
-
-	pipe2rd, pipe2wr := io.Pipe()
-	defer func() { pipe2rd.Close(); pipe2wr.Close() }()
-	convert(strings.NewReader("invalid data"), pipe2wr)
-}
-
-func TestConvertOutputError(t *testing.T) {
-	defer func() {
-		r := recover()
-		if r == nil || r.(error).Error() != "io: read/write on closed pipe" {
-			t.Errorf("The code did not panic as expected; r = %+v", r)
-		}
-	}()
-
-	pipe2rd, pipe2wr := io.Pipe()
-	pipe2wr.Close()
-	defer func() { pipe2rd.Close() }()
-	convert(strings.NewReader("mode: set"), pipe2wr)
-}
-
-func TestConvertEmpty(t *testing.T) {
-	data := `mode: set`
-
-	pipe2rd, pipe2wr := io.Pipe()
-	go convert(strings.NewReader(data), pipe2wr)
-
-	v := Coverage{}
-	dec := xml.NewDecoder(pipe2rd)
-	dec.Decode(&v)
-
-	if v.XMLName.Local != "coverage" {
-		t.Error()
-	}
-	if v.Sources == nil {
-		t.Fatal()
-	}
-	if v.Packages != nil {
-		t.Fatal()
-	}
-}
-
-func TestParseProfileDoesntExist(t *testing.T) {
-	v := Coverage{}
-	profile := Profile{FileName: "does-not-exist"}
-	err := v.parseProfile(&profile)
-	if err == nil || !strings.Contains(err.Error(), `can't find "does-not-exist"`) {
-		t.Fatalf("Expected \"can't find\" error; got: %+v", err)
-	}
-}
-
-func TestParseProfileNotReadable(t *testing.T) {
-	v := Coverage{}
-	profile := Profile{FileName: os.DevNull}
-	err := v.parseProfile(&profile)
-	if err == nil || !strings.Contains(err.Error(), `expected 'package', found 'EOF'`) {
-		t.Fatalf("Expected \"expected 'package', found 'EOF'\" error; got: %+v", err)
-	}
-}
-
-func TestParseProfilePermissionDenied(t *testing.T) {
-	tmpfile, err := ioutil.TempFile("", "not-readable")
-	defer os.Remove(tmpfile.Name())
-	tmpfile.Chmod(000)
-	v := Coverage{}
-	profile := Profile{FileName: tmpfile.Name()}
-	err = v.parseProfile(&profile)
-	if err == nil || !strings.Contains(err.Error(), `permission denied`) {
-		t.Fatalf("Expected \"permission denied\" error; got: %+v", err)
-	}
-}
-
-func TestConvertSetMode(t *testing.T) {
-	pipe1rd, err := os.Open("testdata/testdata_set.txt")
+func TestRunInputsAndOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocover-run")
 	if err != nil {
-		t.Fatal("Can't parse testdata.")
-	}
-
-	pipe2rd, pipe2wr := io.Pipe()
-
-	var convwr io.Writer = pipe2wr
-	if SaveTestResults {
-		testwr, err := os.Create("testdata/testdata_set.xml")
-		if err != nil {
-			t.Fatal("Can't open output testdata.", err)
-		}
-		defer testwr.Close()
-		convwr = io.MultiWriter(convwr, testwr)
-	}
-
-	go convert(pipe1rd, convwr)
-
-	v := Coverage{}
-	dec := xml.NewDecoder(pipe2rd)
-	dec.Decode(&v)
-
-	if v.XMLName.Local != "coverage" {
-		t.Error()
+		t.Fatal(err)
 	}
+	defer os.RemoveAll(dir)
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	ioutil.WriteFile(first, []byte("mode: set\n./cobertura/testdata/func1.go:4.23,5.16 1 1\n./cobertura/testdata/func1.go:5.16,7.3 1 0\n"), 0644)
+	ioutil.WriteFile(second, []byte("mode: set\n./cobertura/testdata/func2.go:7.34,8.16 1 1\n"), 0644)
 
-	if v.Sources == nil {
-		t.Fatal()
+	output := filepath.Join(dir, "coverage.xml")
+	defer func(o, ts string) { *outputFile, *timestamp = o, ts }(*outputFile, *timestamp)
+	*outputFile = output
+	*timestamp = "1500000000"
+	if err := run([]string{first, second}); err != nil {
+		t.Fatal(err)
 	}
 
-	if v.Packages == nil || len(v.Packages) != 1 {
-		t.Fatal()
-	}
-
-	p := v.Packages[0]
-	if strings.TrimRight(p.Name, "/") != "./testdata" {
-		t.Fatal(p.Name)
-	}
-	if p.Classes == nil || len(p.Classes) != 2 {
-		t.Fatal()
-	}
-
-	c := p.Classes[0]
-	if c.Name != "-" {
-		t.Error()
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if c.Filename != "./testdata/func1.go" {
-		t.Errorf("Expected %s but %s", "./testdata/func1.go", c.Filename)
+	v := cobertura.Coverage{}
+	if err := xml.Unmarshal(data[len(xml.Header)+len(cobertura.DocType):], &v); err != nil {
+		t.Fatal(err)
 	}
-	if c.Methods == nil || len(c.Methods) != 1 {
-		t.Fatal()
+	if v.Timestamp != 1500000000000 {
+		t.Errorf("Expected timestamp 1500000000000 but got %d", v.Timestamp)
 	}
-	if c.Lines == nil || len(c.Lines) != 4 {
-		t.Errorf("Expected 4 lines but got %d", len(c.Lines))
+	if len(v.Packages) != 1 || len(v.Packages[0].Classes) != 2 {
+		t.Fatalf("Expected classes from both profiles; got %+v", v.Packages)
 	}
+}
 
-	m := c.Methods[0]
-	if m.Name != "Func1" {
-		t.Error()
-	}
-	if c.Lines == nil || len(c.Lines) != 4 {
-		t.Errorf("Expected 4 lines but got %d", len(c.Lines))
+func TestRunMissingInput(t *testing.T) {
+	err := run([]string{"does-not-exist.txt"})
+	if err == nil || !os.IsNotExist(err) {
+		t.Fatalf("Expected not-exist error; got: %+v", err)
 	}
+}
 
-	var l *Line
-	if l = m.Lines[0]; l.Number != 4 || l.Hits != 1 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
-	}
-	if l = m.Lines[1]; l.Number != 5 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
-	}
-	if l = m.Lines[2]; l.Number != 6 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+func TestParseTimestamp(t *testing.T) {
+	for _, s := range []string{"1500000000", "2017-07-14T02:40:00Z"} {
+		ts, err := parseTimestamp(s)
+		if err != nil || ts.Unix() != 1500000000 {
+			t.Errorf("parseTimestamp(%q) = %v, %v", s, ts, err)
+		}
 	}
-	if l = m.Lines[3]; l.Number != 7 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Error("Expected error for invalid timestamp")
 	}
+}
 
-	if l = c.Lines[0]; l.Number != 4 || l.Hits != 1 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
-	}
-	if l = c.Lines[1]; l.Number != 5 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+func TestThresholdFlag(t *testing.T) {
+	defer func() { thresholds = nil }()
+	for _, s := range []string{"80", "87.5%"} {
+		if err := (thresholdFlag{cobertura.TotalScope}).Set(s); err != nil {
+			t.Errorf("Set(%q): %v", s, err)
+		}
 	}
-	if l = c.Lines[2]; l.Number != 6 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	if err := (thresholdFlag{cobertura.PackageScope}).Set("github.com/a/*=70"); err != nil {
+		t.Error(err)
 	}
-	if l = c.Lines[3]; l.Number != 7 || l.Hits != 0 {
-		t.Errorf("unmatched line: Number:%d, Hits:%d", l.Number, l.Hits)
+	if len(thresholds) != 3 || thresholds[1].Min != 0.875 || thresholds[2].Pattern != "github.com/a/*" || thresholds[2].Min != 0.7 {
+		t.Errorf("Unexpected thresholds %+v", thresholds)
 	}
-
-	c = p.Classes[1]
-	if c.Name != "Type1" {
-		t.Error()
+	for _, s := range []string{"x", "101"} {
+		if err := (thresholdFlag{cobertura.TotalScope}).Set(s); err == nil {
+			t.Errorf("Set(%q): expected error", s)
+		}
 	}
-	if c.Filename != "./testdata/func2.go" {
-		t.Errorf("Expected %s but %s", "./testdata/func2.go", c.Filename)
+	if err := (thresholdFlag{cobertura.FileScope}).Set("80"); err == nil {
+		t.Error("Expected error for a file threshold without glob")
 	}
-	if c.Methods == nil || len(c.Methods) != 3 {
-		t.Fatal()
+	if err := (thresholdFlag{cobertura.PackageScope}).Set("[a=80"); err == nil {
+		t.Error("Expected error for an invalid glob")
 	}
 }